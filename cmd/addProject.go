@@ -15,14 +15,14 @@
 package cmd
 
 import (
-	"net/url"
+	"context"
 	"os"
-	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 )
 
 // addProjectCmd represents the project command
@@ -76,16 +76,18 @@ var addProjectCmd = &cobra.Command{
 			return errors.New("need project URL to add project")
 		}
 
-		if _, err := url.ParseRequestURI(projectURL); err != nil {
-			return errors.New("Project URL is not valid")
+		if _, err := transport.NewEndpoint(projectURL); err != nil {
+			return errors.New("Project URL is not a valid git remote")
 		}
 
-		rjInfo, err := getRjInfo(projectRootPath)
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
 
 		if err != nil {
 			return err
 		}
 
+		defer lock.Unlock()
+
 		if checkProjectExistance(projectURL, rjInfo.RJGlobal.Projects) {
 			return errors.New("project with that URL already exists")
 		}
@@ -93,19 +95,51 @@ var addProjectCmd = &cobra.Command{
 		projectID := generateID()
 
 		if sitePath == "" {
-			sitePath = filepath.Join("./projects/", path.Base(projectURL))
+			sitePath = filepath.Join("./projects/", getRemoteName(projectURL))
 		}
 
 		rjProject := RJProject{
 			ID:       projectID,
-			Name:     path.Base(projectURL),
+			Name:     getRemoteName(projectURL),
 			SitePath: sitePath,
 			URL:      projectURL,
 		}
 
+		if _, err = applyBuildConfigFlags(cmd, &rjProject); err != nil {
+			return err
+		}
+
+		if rjProject.Framework == "" || rjProject.PackageManager == "" {
+			var framework, packageManager string
+
+			if _, err := os.Stat(localPath); localPath != "" && err == nil {
+				framework, packageManager, err = detectLocalProject(localPath)
+			} else {
+				framework, packageManager, err = detectRemoteProject(context.Background(), projectURL, rjProject.Ref)
+			}
+
+			if err != nil {
+				cmd.Println(errors.Wrap(err, "could not detect the framework and package manager of the project"))
+			} else if framework != "" || packageManager != "" {
+				if rjProject.Framework == "" {
+					rjProject.Framework = framework
+				}
+
+				if rjProject.PackageManager == "" {
+					rjProject.PackageManager = packageManager
+				}
+
+				if rjProject.PackageManager == "" {
+					cmd.Printf("Detected framework '%s'\n", rjProject.Framework)
+				} else {
+					cmd.Printf("Detected framework '%s' and package manager '%s'\n", rjProject.Framework, rjProject.PackageManager)
+				}
+			}
+		}
+
 		os.MkdirAll(rjProject.SitePath, os.ModePerm)
 
-		if token != "" {
+		if token != "" && isGithubURL(projectURL) {
 			rjProject.Description, err = getProjectDescription(rjProject.Name, rjInfo.token)
 
 			if err != nil {
@@ -139,6 +173,7 @@ var addProjectCmd = &cobra.Command{
 
 func init() {
 	addProjectCmd.Flags().StringP("description", "d", "", "Either updates a description manually if provided a string, otherwise the description will be fetched from the github page (In which case the '--token' arg will need to be required).")
+	addBuildConfigFlags(addProjectCmd)
 	addProjectCmd.Flags().String("localPath", "", "The string path for the local path for the project; checked by default (a non-existant path will not work), but can be forced.")
 	addProjectCmd.Flags().String("sitePath", "", "The string path for the site path for the project.")
 	addProjectCmd.Flags().StringP("token", "t", "", "Name of the json file in the project root with the gitlab token for gathering the project descriptions, or the token directly.")
@@ -42,12 +42,14 @@ var addSearchDirCmd = &cobra.Command{
 			return errors.New("could not get absolute path to project")
 		}
 
-		rjInfo, err := getRjInfo(projectRootPath)
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
 
 		if err != nil {
 			return err
 		}
 
+		defer lock.Unlock()
+
 		for _, searchPath := range rjInfo.RJLocal.SearchPaths {
 			if searchDir == searchPath {
 				return errors.New("search path already in search path list")
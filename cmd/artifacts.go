@@ -0,0 +1,321 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const artifactExtension = ".tar.gz"
+
+// getCacheDir gets the directory build artifacts are cached in, which is shared by every site on
+// the machine so that artifacts survive switching branches and fresh clones of a site
+func getCacheDir() (string, error) {
+	if cacheDirFlag != "" {
+		return filepath.Abs(cacheDirFlag)
+	}
+
+	userCacheDir, err := os.UserCacheDir()
+
+	if err != nil {
+		return "", errors.Wrap(err, "problem finding the user cache directory, specify one with '--cacheDir'")
+	}
+
+	return filepath.Join(userCacheDir, "rob", "artifacts"), nil
+}
+
+// getArtifactKey gets the key the build output of a release is cached under, which is the input
+// hash of a local project, covering its build digest, or the remote commit and build digest of a
+// remote one; releases built from remote before build digests were recorded only have the commit
+func getArtifactKey(release RJRelease) string {
+	if release.BuildHash != "" {
+		return release.BuildHash
+	}
+
+	if release.BuildConfig == "" {
+		return release.BuildCommit
+	}
+
+	return release.BuildCommit + "-" + release.BuildConfig
+}
+
+// getArtifactPath gets the path of the artifact of the project built from 'key', see 'getArtifactKey'
+func getArtifactPath(cacheDir, projectID, key string) string {
+	return filepath.Join(cacheDir, projectID, key+artifactExtension)
+}
+
+// listArtifacts lists the artifacts in the cache, ordered by project and then newest first
+func listArtifacts(cacheDir string) ([]cachedArtifact, error) {
+	artifacts := make([]cachedArtifact, 0)
+
+	projectDirs, err := ioutil.ReadDir(cacheDir)
+
+	if os.IsNotExist(err) {
+		return artifacts, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, projectDir := range projectDirs {
+		if !projectDir.IsDir() {
+			continue
+		}
+
+		artifactFiles, err := ioutil.ReadDir(filepath.Join(cacheDir, projectDir.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, artifactFile := range artifactFiles {
+			if artifactFile.IsDir() || !strings.HasSuffix(artifactFile.Name(), artifactExtension) {
+				continue
+			}
+
+			artifacts = append(artifacts, cachedArtifact{
+				Created:   artifactFile.ModTime(),
+				Key:       strings.TrimSuffix(artifactFile.Name(), artifactExtension),
+				Path:      filepath.Join(cacheDir, projectDir.Name(), artifactFile.Name()),
+				ProjectID: projectDir.Name(),
+				Size:      artifactFile.Size(),
+			})
+		}
+	}
+
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		if artifacts[i].ProjectID != artifacts[j].ProjectID {
+			return artifacts[i].ProjectID < artifacts[j].ProjectID
+		}
+
+		return artifacts[i].Created.After(artifacts[j].Created)
+	})
+
+	return artifacts, nil
+}
+
+// restoreArtifact extracts the cached artifact of the project built from 'key' into 'destinationDir',
+// replacing its contents; false is returned if there is no such artifact
+func restoreArtifact(cacheDir, projectID, key, destinationDir string) (bool, error) {
+	artifactFile, err := os.Open(getArtifactPath(cacheDir, projectID, key))
+
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	defer artifactFile.Close()
+
+	gzipReader, err := gzip.NewReader(artifactFile)
+
+	if err != nil {
+		return false, err
+	}
+
+	defer gzipReader.Close()
+
+	if err = os.MkdirAll(destinationDir, os.ModePerm); err != nil {
+		return false, err
+	}
+
+	if err = removeContents(destinationDir); err != nil {
+		return false, err
+	}
+
+	tarReader := tar.NewReader(gzipReader)
+
+	for {
+		header, err := tarReader.Next()
+
+		if err == io.EOF {
+			return true, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		cleanName := path.Clean(header.Name)
+
+		if path.IsAbs(cleanName) || cleanName == ".." || strings.HasPrefix(cleanName, "../") {
+			return false, fmt.Errorf("artifact contains '%s', which is outside of the site path", header.Name)
+		}
+
+		targetPath := filepath.Join(destinationDir, filepath.FromSlash(cleanName))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(targetPath, os.FileMode(header.Mode).Perm())
+		case tar.TypeSymlink:
+			err = os.Symlink(header.Linkname, targetPath)
+		case tar.TypeReg:
+			var targetFile *os.File
+
+			targetFile, err = os.OpenFile(targetPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode).Perm())
+
+			if err == nil {
+				_, err = io.Copy(targetFile, tarReader)
+
+				if closeErr := targetFile.Close(); err == nil {
+					err = closeErr
+				}
+			}
+		}
+
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// restoreProjectArtifact restores the cached artifact of the project built from 'key' to the staging
+// directory, reporting whether it was restored; a broken artifact is removed so that it gets rebuilt
+func restoreProjectArtifact(rjProject RJProject, key, stagingPath string, options buildOptions, output io.Writer) bool {
+	if options.cacheDir == "" {
+		return false
+	}
+
+	restored, err := restoreArtifact(options.cacheDir, rjProject.ID, key, stagingPath)
+
+	if err != nil {
+		fmt.Fprintf(output, "Problem restoring the cached build artifact for Project '%s', building instead: %s\n", rjProject.Name, err)
+		os.Remove(getArtifactPath(options.cacheDir, rjProject.ID, key))
+		return false
+	}
+
+	if restored {
+		fmt.Fprintf(output, "Project '%s' restored from the build artifact cache.\n", rjProject.Name)
+	}
+
+	return restored
+}
+
+// storeArtifact archives the contents of 'sourceDir' into the cache as the artifact of the project
+// built from 'key'; the archive is written to a temporary file first so that a partial artifact is
+// never restored
+func storeArtifact(cacheDir, projectID, key, sourceDir string) error {
+	artifactDir := filepath.Join(cacheDir, projectID)
+
+	if err := os.MkdirAll(artifactDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	tempFile, err := ioutil.TempFile(artifactDir, key+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tempFile.Name())
+
+	gzipWriter := gzip.NewWriter(tempFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err = filepath.Walk(sourceDir, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(sourceDir, filePath)
+
+		if err != nil || relativePath == "." {
+			return err
+		}
+
+		var linkTarget string
+
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			if linkTarget, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(fileInfo, linkTarget)
+
+		if err != nil {
+			return err
+		}
+
+		header.Name = filepath.ToSlash(relativePath)
+
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !fileInfo.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(filePath)
+
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+
+	if err == nil {
+		err = tarWriter.Close()
+	}
+
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+
+	if err == nil {
+		err = tempFile.Sync()
+	}
+
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), getArtifactPath(cacheDir, projectID, key))
+}
+
+// storeProjectArtifact caches the contents of the staging directory as the artifact of the project
+// built from 'key'; failing to cache an artifact doesn't fail the build
+func storeProjectArtifact(rjProject RJProject, key, stagingPath string, options buildOptions, output io.Writer) {
+	if options.cacheDir == "" {
+		return
+	}
+
+	if err := storeArtifact(options.cacheDir, rjProject.ID, key, stagingPath); err != nil {
+		fmt.Fprintf(output, "Problem caching the build artifact for Project '%s': %s\n", rjProject.Name, err)
+	}
+}
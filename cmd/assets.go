@@ -0,0 +1,295 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/pkg/errors"
+)
+
+const (
+	assetStageBrotli       = "brotli"
+	assetStageCacheControl = "cacheControl"
+	assetStageGzip         = "gzip"
+	assetStageManifest     = "manifest"
+
+	// Assets smaller than this aren't worth compressing, the response headers outweigh the savings
+	minCompressedAssetSize = 256
+
+	rjAssetManifestFile = ".RJassets.json"
+	rjCacheControlFile  = ".RJcache.json"
+
+	cacheControlHashed   = "public, max-age=31536000, immutable"
+	cacheControlDocument = "no-cache"
+	cacheControlDefault  = "public, max-age=3600"
+)
+
+// compressibleAssetExtensions are the extensions of the text based assets which are precompressed,
+// images, fonts and media are already compressed
+var compressibleAssetExtensions = map[string]bool{
+	".css":         true,
+	".csv":         true,
+	".htm":         true,
+	".html":        true,
+	".js":          true,
+	".json":        true,
+	".map":         true,
+	".md":          true,
+	".mjs":         true,
+	".svg":         true,
+	".txt":         true,
+	".wasm":        true,
+	".webmanifest": true,
+	".xml":         true,
+}
+
+// checkAssetStages returns an error if any of the stages isn't one of the post-build asset stages
+func checkAssetStages(stages []string) error {
+	for _, stage := range stages {
+		switch stage {
+		case assetStageBrotli, assetStageCacheControl, assetStageGzip, assetStageManifest:
+		default:
+			return fmt.Errorf("unknown asset stage '%s', expected '%s', '%s', '%s' or '%s'", stage, assetStageGzip, assetStageBrotli, assetStageManifest, assetStageCacheControl)
+		}
+	}
+
+	return nil
+}
+
+// processAssets runs the project's post-build asset stages on the build output in 'stagingPath';
+// compressed siblings are written first so that the manifest can record their sizes, and the
+// files the stages write, as well as files the build already compressed, are never processed themselves
+func processAssets(ctx context.Context, rjProject RJProject, stagingPath string, output io.Writer) error {
+	if len(rjProject.AssetStages) == 0 {
+		return nil
+	}
+
+	stages := make(map[string]bool)
+
+	for _, stage := range rjProject.AssetStages {
+		stages[stage] = true
+	}
+
+	outputPaths, err := listAssets(stagingPath)
+
+	if err != nil {
+		return errors.Wrap(err, "problem listing the build output")
+	}
+
+	assetPaths := make([]string, 0, len(outputPaths))
+
+	for _, outputPath := range outputPaths {
+		if extension := strings.ToLower(path.Ext(outputPath)); extension != ".gz" && extension != ".br" {
+			assetPaths = append(assetPaths, outputPath)
+		}
+	}
+
+	manifest := assetManifest{Assets: make(map[string]assetManifestEntry)}
+	cacheControl := make(map[string]string)
+	compressed := 0
+
+	for _, assetPath := range assetPaths {
+		if err := getContextError(ctx, "asset processing"); err != nil {
+			return err
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(stagingPath, filepath.FromSlash(assetPath)))
+
+		if err != nil {
+			return err
+		}
+
+		hash := sha256.Sum256(contents)
+		entry := assetManifestEntry{
+			Integrity: "sha256-" + base64.StdEncoding.EncodeToString(hash[:]),
+			SHA256:    hex.EncodeToString(hash[:]),
+			Size:      int64(len(contents)),
+		}
+
+		if len(contents) >= minCompressedAssetSize && compressibleAssetExtensions[strings.ToLower(path.Ext(assetPath))] {
+			if stages[assetStageGzip] {
+				if entry.GzipSize, err = writeCompressedAsset(stagingPath, assetPath+".gz", contents, newGzipWriter); err != nil {
+					return errors.Wrapf(err, "problem gzipping '%s'", assetPath)
+				}
+			}
+
+			if stages[assetStageBrotli] {
+				if entry.BrotliSize, err = writeCompressedAsset(stagingPath, assetPath+".br", contents, newBrotliWriter); err != nil {
+					return errors.Wrapf(err, "problem compressing '%s' with brotli", assetPath)
+				}
+			}
+
+			if entry.GzipSize != 0 || entry.BrotliSize != 0 {
+				compressed++
+			}
+		}
+
+		manifest.Assets[assetPath] = entry
+		cacheControl[assetPath] = getCacheControl(assetPath)
+	}
+
+	if stages[assetStageManifest] {
+		if err = writeAssetFile(filepath.Join(stagingPath, rjAssetManifestFile), manifest); err != nil {
+			return errors.Wrap(err, "problem writing the asset manifest")
+		}
+	}
+
+	if stages[assetStageCacheControl] {
+		if err = writeAssetFile(filepath.Join(stagingPath, rjCacheControlFile), cacheControl); err != nil {
+			return errors.Wrap(err, "problem writing the cache-control hints")
+		}
+	}
+
+	fmt.Fprintf(output, "Processed %d assets of Project '%s' (%s), %d precompressed.\n", len(assetPaths), rjProject.Name, strings.Join(rjProject.AssetStages, ", "), compressed)
+
+	return nil
+}
+
+// listAssets lists the slash-separated paths of the files in the build output, in lexical order
+func listAssets(outputPath string) ([]string, error) {
+	assetPaths := make([]string, 0)
+
+	err := filepath.Walk(outputPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil || fileInfo.IsDir() {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(outputPath, filePath)
+
+		if err != nil {
+			return err
+		}
+
+		if relativePath == rjAssetManifestFile || relativePath == rjCacheControlFile || !fileInfo.Mode().IsRegular() {
+			return nil
+		}
+
+		assetPaths = append(assetPaths, filepath.ToSlash(relativePath))
+
+		return nil
+	})
+
+	sort.Strings(assetPaths)
+
+	return assetPaths, err
+}
+
+func newGzipWriter(writer io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(writer, gzip.BestCompression)
+}
+
+func newBrotliWriter(writer io.Writer) (io.WriteCloser, error) {
+	return brotli.NewWriterLevel(writer, brotli.BestCompression), nil
+}
+
+// writeCompressedAsset writes the compressed contents of an asset next to it, returning the size of
+// the compressed file; nothing is written, and 0 returned, if compressing doesn't make it smaller
+func writeCompressedAsset(outputPath, compressedPath string, contents []byte, newWriter func(io.Writer) (io.WriteCloser, error)) (int64, error) {
+	var buffer bytes.Buffer
+
+	writer, err := newWriter(&buffer)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = writer.Write(contents); err != nil {
+		return 0, err
+	}
+
+	if err = writer.Close(); err != nil {
+		return 0, err
+	}
+
+	if buffer.Len() >= len(contents) {
+		return 0, nil
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(outputPath, filepath.FromSlash(compressedPath)), buffer.Bytes(), 0644); err != nil {
+		return 0, err
+	}
+
+	return int64(buffer.Len()), nil
+}
+
+// writeAssetFile writes the JSON encoded value to a file of the build output
+func writeAssetFile(filePath string, value interface{}) error {
+	fileBytes, err := json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, append(fileBytes, '\n'), 0644)
+}
+
+// getCacheControl gets the Cache-Control header hint for an asset; documents are revalidated on every
+// request so that new releases are picked up, assets with a content hash in their name never change
+// so they are cached forever, and everything else is cached for an hour
+func getCacheControl(assetPath string) string {
+	switch extension := strings.ToLower(path.Ext(assetPath)); {
+	case extension == ".html" || extension == ".htm" || extension == ".webmanifest":
+		return cacheControlDocument
+	case isHashedAsset(assetPath):
+		return cacheControlHashed
+	}
+
+	return cacheControlDefault
+}
+
+// isHashedAsset checks if the name of an asset has a content hash the way bundlers write them: at
+// least 8 lowercase hex characters after a '.', such as 'main.3f2a9c1b.chunk.js' from webpack, or 8
+// base64url characters after the last '-', such as 'index-BdX3k2aQ.js' from Vite and Rollup; those
+// after a '-' have to have something other than lowercase letters, so that names like 'my-homepage' aren't hashed
+func isHashedAsset(assetPath string) bool {
+	name := path.Base(assetPath)
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	for _, part := range strings.Split(name, ".")[1:] {
+		if len(part) >= 8 && strings.Trim(part, "0123456789abcdef") == "" {
+			return true
+		}
+	}
+
+	index := strings.LastIndex(name, "-")
+
+	if index == -1 || len(name)-index-1 != 8 {
+		return false
+	}
+
+	segment := name[index+1:]
+
+	if strings.Trim(segment, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_") != "" {
+		return false
+	}
+
+	return strings.Trim(segment, "abcdefghijklmnopqrstuvwxyz") != ""
+}
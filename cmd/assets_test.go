@@ -0,0 +1,46 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "testing"
+
+func TestGetCacheControl(t *testing.T) {
+	testCases := []struct {
+		assetPath    string
+		cacheControl string
+	}{
+		{"index.html", cacheControlDocument},
+		{"manifest.webmanifest", cacheControlDocument},
+		{"static/js/main.3f2a9c1b.js", cacheControlHashed},
+		{"static/js/787.3f2a9c1b.chunk.js", cacheControlHashed},
+		{"static/css/main.0123abcdef456789.css", cacheControlHashed},
+		{"assets/index-BdX3k2aQ.js", cacheControlHashed},
+		{"assets/vendor-a_3kZ9Qx.css", cacheControlHashed},
+		{"ServiceWorker.js", cacheControlDefault},
+		{"appleTouchIcon.png", cacheControlDefault},
+		{"report2023.pdf", cacheControlDefault},
+		{"my-homepage.js", cacheControlDefault},
+		{"logo.svg", cacheControlDefault},
+		{"main.3F2A9C1B.js", cacheControlDefault},
+		{"main.3f2a9c.js", cacheControlDefault},
+		{"index-BdX3k2aQz.js", cacheControlDefault},
+	}
+
+	for _, testCase := range testCases {
+		if cacheControl := getCacheControl(testCase.assetPath); cacheControl != testCase.cacheControl {
+			t.Errorf("expected '%s' to be cached with '%s', got '%s'", testCase.assetPath, testCase.cacheControl, cacheControl)
+		}
+	}
+}
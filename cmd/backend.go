@@ -0,0 +1,68 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// backendCmd represents the backend command
+var backendCmd = &cobra.Command{
+	Use:   "backend",
+	Short: "Prints the container backend used for this workspace, or sets it if one is specified.",
+	Long: `Prints the container backend used for this workspace, or sets it in RJlocal if one is specified.
+The backend can be either 'docker' (default) or 'podman'; the '--backend' flag overrides it for a single command.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backend := strings.ToLower(strings.TrimSpace(strings.Join(args, " ")))
+
+		if backend == "" {
+			rjInfo, err := getRjInfo(projectRootPath)
+
+			if err != nil {
+				return err
+			}
+
+			if rjInfo.RJLocal.Backend == "" {
+				cmd.Printf("%s (default)\n", dockerBackend)
+			} else {
+				cmd.Println(rjInfo.RJLocal.Backend)
+			}
+
+			return nil
+		}
+
+		if _, err := getBuilder(backend); err != nil {
+			return err
+		}
+
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
+
+		if err != nil {
+			return err
+		}
+
+		defer lock.Unlock()
+
+		rjInfo.RJLocal.Backend = backend
+
+		return writeUpdate(projectRootPath, *rjInfo)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backendCmd)
+}
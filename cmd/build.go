@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -27,8 +28,8 @@ import (
 var buildCmd = &cobra.Command{
 	Use:   "build",
 	Short: "Builds either the local project specified or all local projects if no project is specified.",
-	Long: `Builds either the local project specified or all local projects if no project is specified.
-Will check the last build hash prior to building and if they are the same then the project will only be rebuilt if the '-force' flag is included.`,
+	Long: `Builds either the local project specified or all local projects if no project is specified, skipping projects whose hash (or remote commit) and build config are unchanged.
+Builds are released to the project's site path once their output passes the project's checks, see 'logs', 'history' and 'rollback' for what they leave behind.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		force, err := cmd.Flags().GetBool("force")
 
@@ -36,18 +37,171 @@ Will check the last build hash prior to building and if they are the same then t
 			return err
 		}
 
+		jobs, err := cmd.Flags().GetInt("jobs")
+
+		if err != nil {
+			return err
+		}
+
+		if jobs < 1 {
+			return errors.New("'--jobs' must be at least 1")
+		}
+
+		keepLogs, err := cmd.Flags().GetInt("keepLogs")
+
+		if err != nil {
+			return err
+		}
+
+		if keepLogs < 1 {
+			return errors.New("'--keepLogs' must be at least 1")
+		}
+
+		keepReleases, err := cmd.Flags().GetInt("keepReleases")
+
+		if err != nil {
+			return err
+		}
+
+		if keepReleases < 1 {
+			return errors.New("'--keepReleases' must be at least 1")
+		}
+
+		native, err := cmd.Flags().GetBool("native")
+
+		if err != nil {
+			return err
+		}
+
+		noCache, err := cmd.Flags().GetBool("noCache")
+
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString("output")
+
+		if err != nil {
+			return err
+		}
+
+		if output != "text" && output != "json" {
+			return errors.New("'--output' must be either 'text' or 'json'")
+		}
+
+		plan, err := cmd.Flags().GetBool("plan")
+
+		if err != nil {
+			return err
+		}
+
+		ref, err := cmd.Flags().GetString("ref")
+
+		if err != nil {
+			return err
+		}
+
+		retryAttempts, err := cmd.Flags().GetInt("retryAttempts")
+
+		if err != nil {
+			return err
+		}
+
+		if retryAttempts < 0 {
+			return errors.New("'--retryAttempts' can not be negative")
+		}
+
+		retryBackoff, err := cmd.Flags().GetDuration("retryBackoff")
+
+		if err != nil {
+			return err
+		}
+
+		if retryBackoff < 0 {
+			return errors.New("'--retryBackoff' can not be negative")
+		}
+
+		retryPhases, err := cmd.Flags().GetStringSlice("retryPhases")
+
+		if err != nil {
+			return err
+		}
+
+		if err = checkRetryPhases(retryPhases); err != nil {
+			return err
+		}
+
 		root, err := cmd.Flags().GetBool("root")
 
 		if err != nil {
 			return err
 		}
 
-		rjInfo, err := getRjInfo(projectRootPath)
+		targetFlags, err := cmd.Flags().GetStringSlice("target")
+
+		if err != nil {
+			return err
+		}
+
+		if len(targetFlags) != 0 && !root {
+			return errors.New("'--target' can only be used with '--root'")
+		}
+
+		targets := make([]buildTarget, 0, len(targetFlags))
+
+		for _, targetFlag := range targetFlags {
+			target, err := parseBuildTarget(targetFlag)
+
+			if err != nil {
+				return err
+			}
+
+			targets = append(targets, target)
+		}
+
+		timeout, err := cmd.Flags().GetDuration("timeout")
+
+		if err != nil {
+			return err
+		}
+
+		project := strings.TrimSpace(strings.Join(args, " "))
+
+		ctx, cancel := newCommandContext(timeout)
+		defer cancel()
+
+		if plan {
+			if root {
+				return errors.New("'--plan' can not be used with '--root'")
+			}
+
+			return planBuild(projectRootPath, project, buildOptions{ctx: ctx, force: force, native: native, ref: strings.TrimSpace(ref)}, output, os.Stdout)
+		}
+
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
+
+		if err != nil {
+			return err
+		}
+
+		defer lock.Unlock()
+
+		builder, err := getWorkspaceBuilder(rjInfo)
 
 		if err != nil {
 			return err
 		}
 
+		options := buildOptions{builder: builder, ctx: ctx, force: force, jobs: jobs, keepLogs: keepLogs, keepReleases: keepReleases, native: native, projectRoot: projectRootPath, ref: strings.TrimSpace(ref), siteURL: rjInfo.RJGlobal.SiteURL}
+
+		options.retryAttempts, options.retryBackoff, options.retryPhases = retryAttempts, retryBackoff, retryPhases
+
+		if !noCache {
+			if options.cacheDir, err = getCacheDir(); err != nil {
+				return err
+			}
+		}
+
 		if root {
 			if _, err := os.Stat(projectRootPath); err != nil {
 				return errors.Wrap(err, "path to project root does not exist")
@@ -59,46 +213,90 @@ Will check the last build hash prior to building and if they are the same then t
 				return errors.Wrap(err, "problem fetching remote commit hash for root project")
 			}
 
-			if localHash != rjInfo.RJLocal.LastRemoteHashOnBuild || force {
-				if remoteHash, err := getRemoteProjectCommit(rjInfo.RJGlobal.URL); err == nil && remoteHash != localHash {
-					fmt.Println("Local project is not synced with remote, make sure to push/pull as needed.")
-				}
+			record := buildRecord{
+				Commit:      localHash,
+				Mode:        buildModeLocal,
+				Previous:    rjInfo.RJLocal.LastRemoteHashOnBuild,
+				ProjectID:   rootProjectID,
+				ProjectName: rjServer,
+				Start:       time.Now().UTC(),
+				Trigger:     getRootBuildReason(localHash, rjInfo.RJLocal.LastRemoteHashOnBuild, force),
+			}
 
-				if err = buildRoot(projectRootPath); err != nil {
-					return err
+			if record.Trigger == buildReasonUnchanged {
+				cmd.Println("Skipping building root project because the last build hash matches the local commit hash, please specify '-force' if you wish to override.")
+
+				record.End, record.Result = record.Start, buildResultSkipped
+
+				return appendBuildHistory(projectRootPath, record)
+			}
+
+			if remoteHash, err := getRemoteProjectCommit(ctx, rjInfo.RJGlobal.URL, ""); err == nil && remoteHash != localHash {
+				fmt.Println("Local project is not synced with remote, make sure to push/pull as needed.")
+			}
+
+			executablePaths, err := buildRoot(ctx, projectRootPath, builder, targets, rjInfo.RJGlobal.RootBuild)
+
+			record.End, record.Result = time.Now().UTC(), buildResultSucceeded
+
+			if err != nil {
+				record.Error, record.Result = err.Error(), getFailedBuildResult(err)
+			} else {
+				for _, executablePath := range executablePaths {
+					if executableInfo, statErr := os.Stat(executablePath); statErr == nil {
+						record.OutputSize += executableInfo.Size()
+					}
 				}
+			}
 
-				rjInfo.RJLocal.LastRemoteHashOnBuild = localHash
-				return writeUpdate(projectRootPath, *rjInfo)
+			if historyErr := appendBuildHistory(projectRootPath, record); historyErr != nil {
+				cmd.Println(errors.Wrap(historyErr, "problem adding the build to the build history"))
 			}
 
-			cmd.Println("Skipping building root project because the last build hash matches the local commit hash, please specify '-force' if you wish to override.")
-			return nil
-		}
+			if err != nil {
+				return err
+			}
 
-		var rerr error
+			rjInfo.RJLocal.LastRemoteHashOnBuild = localHash
+			return writeUpdate(projectRootPath, *rjInfo)
+		}
 
-		project := strings.TrimSpace(strings.Join(args, " "))
-		var needUpdate, update bool
+		var update bool
 
 		if project == "" {
-			for _, rjProject := range rjInfo.RJGlobal.Projects {
-				if needUpdate, err = rjBuild(rjInfo, rjProject, projectRootPath, force); needUpdate {
+			if options.ref != "" {
+				return errors.New("'--ref' can only be used when building a single project")
+			}
+
+			results := rjBuildAll(rjInfo, projectRootPath, options)
+			failed := 0
+
+			for _, result := range results {
+				if result.Built {
 					update = true
-				} else if err != nil {
-					rerr = err
+				} else if result.Err != nil {
+					failed++
 				}
 			}
 
+			fmt.Println()
+			printBuildSummary(os.Stdout, results)
+
 			if update {
-				return writeUpdate(projectRootPath, *rjInfo)
+				if err = writeUpdate(projectRootPath, *rjInfo); err != nil {
+					return err
+				}
+			}
+
+			if failed != 0 {
+				return fmt.Errorf("%d of %d projects failed to build", failed, len(results))
 			}
 
-			return rerr
+			return nil
 		}
 
 		if index := getProjectIndex(project, rjInfo.RJGlobal.Projects); index != -1 {
-			update, err = rjBuild(rjInfo, rjInfo.RJGlobal.Projects[index], projectRootPath, force)
+			update, err = rjBuildLogged(rjInfo, rjInfo.RJGlobal.Projects[index], projectRootPath, options, os.Stdout)
 
 			if update {
 				return writeUpdate(projectRootPath, *rjInfo)
@@ -113,6 +311,19 @@ Will check the last build hash prior to building and if they are the same then t
 
 func init() {
 	buildCmd.Flags().BoolP("force", "f", false, "Forces the project to be built.")
+	buildCmd.Flags().IntP("jobs", "j", 1, "The number of projects to build at the same time when building every project.")
+	buildCmd.Flags().Int("keepLogs", defaultKeepLogs, "The number of build logs of each project kept for 'logs'.")
+	buildCmd.Flags().Int("keepReleases", defaultKeepReleases, "The number of releases of each project kept for 'rollback', including the one in the site path.")
+	buildCmd.Flags().Bool("native", false, "Builds the project(s) with the node installation on this machine instead of in a container.")
+	buildCmd.Flags().Bool("noCache", false, "Neither restores projects from nor adds them to the build artifact cache.")
+	buildCmd.Flags().StringP("output", "o", "text", "The output format of '--plan', either 'text' or 'json'.")
+	buildCmd.Flags().Bool("plan", false, "Prints which projects would be built or skipped and why, without building anything or changing RJlocal.")
+	buildCmd.Flags().String("ref", "", "The branch, tag or commit to build when the project is built remotely, overrides the project's ref.")
+	buildCmd.Flags().Int("retryAttempts", 0, fmt.Sprintf("The maximum number of attempts of each retried phase, overrides the projects' retry policies (default %d).", defaultRetryAttempts))
+	buildCmd.Flags().Duration("retryBackoff", 0, fmt.Sprintf("The wait before the first retry, doubled after every retry, overrides the projects' retry policies (default %s).", defaultRetryBackoff))
+	buildCmd.Flags().StringSlice("retryPhases", nil, fmt.Sprintf("Comma separated phases which are retried, overrides the projects' retry policies (default '%s').", strings.Join(defaultRetryPhases, ",")))
 	buildCmd.Flags().Bool("root", false, "Builds the webserver in the project root.")
+	buildCmd.Flags().StringSlice("target", nil, "Comma separated platforms to build the webserver for with '--root', such as 'linux/amd64,linux/arm/v7' (default this machine's platform).")
+	buildCmd.Flags().Duration("timeout", 0, "How long the build may take before it is stopped, 0 for no limit.")
 	rootCmd.AddCommand(buildCmd)
 }
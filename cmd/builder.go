@@ -0,0 +1,391 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	dockerBackend = "docker"
+	podmanBackend = "podman"
+
+	// robImageLabel is the image an image was built as, or a container was run from, which stays
+	// on an image after it is untagged by a newer build of the same image
+	robImageLabel = "rob.image"
+	// robManagedLabel is on every image and container ROB creates, see 'gc'
+	robManagedLabel = "rob.managed"
+	// robProjectLabel is the ID of the project an image or container was created for
+	robProjectLabel = "rob.project"
+
+	// containerCleanupTimeout is how long stopping or removing a container may take before the CLI is
+	// killed, the CLI gives a container 10 seconds to stop before killing it
+	containerCleanupTimeout = 20 * time.Second
+)
+
+// Builder is a container backend which builds, runs and pushes the images ROB uses; operations
+// taking a context are abandoned once it is done, stopping any container they started
+type Builder interface {
+	// BuildImage builds and tags an image from the Dockerfile and build context provided
+	BuildImage(ctx context.Context, options imageBuildOptions) error
+	// Push pushes the image to its registry
+	Push(ctx context.Context, image string, output io.Writer) error
+	// RemoveImage removes the image locally
+	RemoveImage(image string) error
+	// Run runs a container from the image until it exits, the container is removed afterwards
+	Run(ctx context.Context, options containerRunOptions) error
+	// Stop stops the running container with the name provided
+	Stop(name string) error
+	// ListImages lists the images with the label provided, newest first
+	ListImages(label string) ([]builderResource, error)
+	// ListStoppedContainers lists the containers with the label provided which aren't running, newest first
+	ListStoppedContainers(label string) ([]builderResource, error)
+	// RemoveContainer removes the stopped container with the ID provided
+	RemoveContainer(id string) error
+}
+
+// imageBuildOptions describes an image for a Builder to build
+type imageBuildOptions struct {
+	BuildArgs  map[string]string
+	ContextDir string
+	Dockerfile string
+	Image      string
+	Labels     map[string]string
+	NoCache    bool
+	Stderr     io.Writer
+	Stdout     io.Writer
+}
+
+// containerRunOptions describes a container for a Builder to run, the contents of the container
+// paths in 'CopyOut' are copied to the host paths they map to once the container exits
+type containerRunOptions struct {
+	CopyOut map[string]string
+	Image   string
+	Labels  map[string]string
+	Name    string
+	Stderr  io.Writer
+	Stdout  io.Writer
+}
+
+// builderResource is an image or container listed by a Builder, images are named by their tags
+type builderResource struct {
+	Created time.Time
+	ID      string
+	Labels  map[string]string
+	Names   []string
+}
+
+// getRobLabels gets the labels ROB adds to the images it builds and the containers it runs, which
+// is how 'gc' finds them; the project label is left out if there is no project ID
+func getRobLabels(image, projectID string) map[string]string {
+	labels := map[string]string{
+		robImageLabel:   image,
+		robManagedLabel: "true",
+	}
+
+	if projectID != "" {
+		labels[robProjectLabel] = projectID
+	}
+
+	return labels
+}
+
+// getBuilder returns the Builder for the backend name provided, defaulting to docker
+func getBuilder(backend string) (Builder, error) {
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "", dockerBackend:
+		return newDockerBuilder(), nil
+	case podmanBackend:
+		return newPodmanBuilder(), nil
+	}
+
+	return nil, fmt.Errorf("unknown backend '%s', expected either '%s' or '%s'", backend, dockerBackend, podmanBackend)
+}
+
+// getWorkspaceBuilder returns the Builder for the '--backend' flag if specified, otherwise the backend set for the workspace in RJlocal
+func getWorkspaceBuilder(rjInfo *RJInfo) (Builder, error) {
+	if backendFlag != "" || rjInfo == nil {
+		return getBuilder(backendFlag)
+	}
+
+	return getBuilder(rjInfo.RJLocal.Backend)
+}
+
+//=================================================
+// Builder backed by a docker compatible CLI binary
+
+// cliBuilder runs the docker compatible CLI binary for every operation, killing the CLI process
+// (and stopping the container it started) once the context of the operation is done
+type cliBuilder struct {
+	binary string
+	// dockerfileFromStdin is false for CLIs which can't read the Dockerfile from stdin
+	dockerfileFromStdin bool
+}
+
+func newDockerBuilder() *cliBuilder {
+	return &cliBuilder{binary: "docker", dockerfileFromStdin: true}
+}
+
+func newPodmanBuilder() *cliBuilder {
+	return &cliBuilder{binary: "podman", dockerfileFromStdin: false}
+}
+
+// BuildImage is equivalent to "{binary} build -t {image} --build-arg {key}={value} -f - {context}"
+func (builder *cliBuilder) BuildImage(ctx context.Context, options imageBuildOptions) error {
+	imageBuildArgs := []string{"build", "-t", options.Image}
+
+	if options.NoCache {
+		imageBuildArgs = append(imageBuildArgs, "--no-cache")
+	}
+
+	imageBuildArgs = appendKeyValueArgs(imageBuildArgs, "--build-arg", options.BuildArgs)
+	imageBuildArgs = appendKeyValueArgs(imageBuildArgs, "--label", options.Labels)
+
+	contextDir := filepath.Clean(options.ContextDir)
+
+	if builder.dockerfileFromStdin {
+		imageBuildArgs = append(imageBuildArgs, "-f", "-", contextDir)
+	} else {
+		dockerfile, err := ioutil.TempFile("", "rob-dockerfile-")
+
+		if err != nil {
+			return errors.Wrap(err, "problem writing the Dockerfile")
+		}
+
+		defer os.Remove(dockerfile.Name())
+
+		_, err = dockerfile.WriteString(options.Dockerfile)
+		dockerfile.Close()
+
+		if err != nil {
+			return errors.Wrap(err, "problem writing the Dockerfile")
+		}
+
+		imageBuildArgs = append(imageBuildArgs, "-f", dockerfile.Name(), contextDir)
+	}
+
+	cmd := exec.Command(builder.binary, imageBuildArgs...)
+
+	if builder.dockerfileFromStdin {
+		cmd.Stdin = bytes.NewBufferString(options.Dockerfile)
+	}
+
+	cmd.Stdout = options.Stdout
+	cmd.Stderr = options.Stderr
+
+	return runCommand(ctx, cmd, nil)
+}
+
+// Push is equivalent to "{binary} push {image}"
+func (builder *cliBuilder) Push(ctx context.Context, image string, output io.Writer) error {
+	cmd := exec.Command(builder.binary, "push", image)
+
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	return runCommand(ctx, cmd, nil)
+}
+
+// RemoveImage is equivalent to "{binary} rmi {image}"
+func (builder *cliBuilder) RemoveImage(image string) error {
+	return exec.Command(builder.binary, "rmi", image).Run()
+}
+
+// Run is equivalent to "{binary} run --rm --name {name} {image}", without '--rm' if there are paths
+// to copy out, which are copied with "{binary} cp {name}:{container path}/. {host path}" before
+// the container is removed
+func (builder *cliBuilder) Run(ctx context.Context, options containerRunOptions) error {
+	runArgs := []string{"run"}
+
+	// Containers with paths to copy out are removed once they have been copied instead
+	if len(options.CopyOut) == 0 {
+		runArgs = append(runArgs, "--rm")
+	}
+
+	runArgs = appendKeyValueArgs(runArgs, "--label", options.Labels)
+	runArgs = append(runArgs, "--name", options.Name, options.Image)
+
+	cmd := exec.Command(builder.binary, runArgs...)
+
+	cmd.Stdout = options.Stdout
+	cmd.Stderr = options.Stderr
+
+	// Killing the CLI leaves the container running, so it is stopped as well
+	err := runCommand(ctx, cmd, func() {
+		if err := builder.Stop(options.Name); err != nil && options.Stderr != nil {
+			fmt.Fprintln(options.Stderr, errors.Wrapf(err, "problem stopping container '%s'", options.Name))
+		}
+	})
+
+	if len(options.CopyOut) == 0 {
+		return err
+	}
+
+	defer builder.RemoveContainer(options.Name)
+
+	if err != nil {
+		return err
+	}
+
+	containerPaths := make([]string, 0, len(options.CopyOut))
+
+	for containerPath := range options.CopyOut {
+		containerPaths = append(containerPaths, containerPath)
+	}
+
+	sort.Strings(containerPaths)
+
+	// Unlike a bind mount the CLI writes the copies, so they're owned by the user running ROB rather
+	// than by the container's user, which is root for the default images
+	for _, containerPath := range containerPaths {
+		hostPath := filepath.Clean(options.CopyOut[containerPath])
+		cmd := exec.Command(builder.binary, "cp", fmt.Sprintf("%s:%s/.", options.Name, strings.TrimSuffix(containerPath, "/")), hostPath)
+
+		cmd.Stdout = options.Stdout
+		cmd.Stderr = options.Stderr
+
+		if err = runCommand(ctx, cmd, nil); err != nil {
+			return errors.Wrapf(err, "problem copying '%s' out of container '%s'", containerPath, options.Name)
+		}
+	}
+
+	return nil
+}
+
+// Stop is equivalent to "{binary} stop {name}", given up on after 'containerCleanupTimeout'
+func (builder *cliBuilder) Stop(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), containerCleanupTimeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, builder.binary, "stop", name).Run()
+}
+
+// ListImages is equivalent to "{binary} images --filter label={label}" followed by "{binary} image inspect"
+func (builder *cliBuilder) ListImages(label string) ([]builderResource, error) {
+	return builder.inspect("image", "images", "--quiet", "--no-trunc", "--filter", "label="+label)
+}
+
+// ListStoppedContainers is equivalent to "{binary} ps --all --filter label={label} --filter status=exited"
+// followed by "{binary} container inspect"
+func (builder *cliBuilder) ListStoppedContainers(label string) ([]builderResource, error) {
+	return builder.inspect("container", "ps", "--all", "--quiet", "--no-trunc", "--filter", "label="+label, "--filter", "status=created", "--filter", "status=exited")
+}
+
+// RemoveContainer is equivalent to "{binary} rm {id}", given up on after 'containerCleanupTimeout'
+func (builder *cliBuilder) RemoveContainer(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), containerCleanupTimeout)
+	defer cancel()
+
+	return exec.CommandContext(ctx, builder.binary, "rm", id).Run()
+}
+
+// inspect lists the IDs of images or containers with the list arguments provided and inspects
+// them, the inspect output of docker and podman have the fields used in common
+func (builder *cliBuilder) inspect(kind string, listArgs ...string) ([]builderResource, error) {
+	listOutput, err := exec.Command(builder.binary, listArgs...).Output()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "problem listing %ss", kind)
+	}
+
+	// Images with several tags are listed once for every tag
+	ids := make([]string, 0)
+	listed := make(map[string]bool)
+
+	for _, id := range strings.Fields(string(listOutput)) {
+		if !listed[id] {
+			ids = append(ids, id)
+			listed[id] = true
+		}
+	}
+
+	resources := make([]builderResource, 0, len(ids))
+
+	if len(ids) == 0 {
+		return resources, nil
+	}
+
+	inspectOutput, err := exec.Command(builder.binary, append([]string{kind, "inspect"}, ids...)...).Output()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "problem inspecting %ss", kind)
+	}
+
+	var inspected []struct {
+		Config struct {
+			Labels map[string]string
+		}
+		Created  time.Time
+		ID       string `json:"Id"`
+		Name     string
+		RepoTags []string
+	}
+
+	if err = json.Unmarshal(inspectOutput, &inspected); err != nil {
+		return nil, errors.Wrapf(err, "problem decoding the inspected %ss", kind)
+	}
+
+	for _, resource := range inspected {
+		names := resource.RepoTags
+
+		if resource.Name != "" {
+			names = []string{strings.TrimPrefix(resource.Name, "/")}
+		}
+
+		resources = append(resources, builderResource{
+			Created: resource.Created,
+			ID:      resource.ID,
+			Labels:  resource.Config.Labels,
+			Names:   names,
+		})
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Created.After(resources[j].Created)
+	})
+
+	return resources, nil
+}
+
+// appendKeyValueArgs appends the flag with a 'key=value' argument for every entry of the map, in
+// the order of the keys
+func appendKeyValueArgs(args []string, flag string, values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		args = append(args, flag, fmt.Sprintf("%s=%s", key, values[key]))
+	}
+
+	return args
+}
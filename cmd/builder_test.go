@@ -0,0 +1,114 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// fakeBuilderCall is a single call recorded by fakeBuilder
+type fakeBuilderCall struct {
+	Method string
+	Args   []string
+}
+
+// fakeBuilder records every call made to it and succeeds without doing anything else besides
+// writing an 'index.html' to the directories copied out of containers, which allows exercising the
+// build logic in tests without a container daemon
+type fakeBuilder struct {
+	Calls []fakeBuilderCall
+	// Errors maps a method name to the error returned by every call to it
+	Errors map[string]error
+	lock   sync.Mutex
+}
+
+func newFakeBuilder() *fakeBuilder {
+	return &fakeBuilder{Calls: make([]fakeBuilderCall, 0), Errors: make(map[string]error)}
+}
+
+func (builder *fakeBuilder) record(ctx context.Context, output io.Writer, method string, args ...string) error {
+	if ctx != nil {
+		if err := getContextError(ctx, method); err != nil {
+			return err
+		}
+	}
+
+	builder.lock.Lock()
+	defer builder.lock.Unlock()
+
+	builder.Calls = append(builder.Calls, fakeBuilderCall{method, args})
+
+	if output != nil {
+		fmt.Fprintf(output, "fake backend: %s %s\n", method, strings.Join(args, " "))
+	}
+
+	return builder.Errors[method]
+}
+
+// BuildImage records the image and build context
+func (builder *fakeBuilder) BuildImage(ctx context.Context, options imageBuildOptions) error {
+	return builder.record(ctx, options.Stdout, "BuildImage", options.Image, options.ContextDir)
+}
+
+// Push records the image
+func (builder *fakeBuilder) Push(ctx context.Context, image string, output io.Writer) error {
+	return builder.record(ctx, output, "Push", image)
+}
+
+// RemoveImage records the image
+func (builder *fakeBuilder) RemoveImage(image string) error {
+	return builder.record(nil, nil, "RemoveImage", image)
+}
+
+// Run records the image and container name and writes the build output to the directories copied out
+func (builder *fakeBuilder) Run(ctx context.Context, options containerRunOptions) error {
+	if err := builder.record(ctx, options.Stdout, "Run", options.Image, options.Name); err != nil {
+		return err
+	}
+
+	for _, hostPath := range options.CopyOut {
+		if err := ioutil.WriteFile(filepath.Join(hostPath, "index.html"), []byte(options.Name), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stop records the container name
+func (builder *fakeBuilder) Stop(name string) error {
+	return builder.record(nil, nil, "Stop", name)
+}
+
+// ListImages records the label, nothing is ever listed since nothing is ever built
+func (builder *fakeBuilder) ListImages(label string) ([]builderResource, error) {
+	return make([]builderResource, 0), builder.record(nil, nil, "ListImages", label)
+}
+
+// ListStoppedContainers records the label, nothing is ever listed since nothing is ever run
+func (builder *fakeBuilder) ListStoppedContainers(label string) ([]builderResource, error) {
+	return make([]builderResource, 0), builder.record(nil, nil, "ListStoppedContainers", label)
+}
+
+// RemoveContainer records the container ID
+func (builder *fakeBuilder) RemoveContainer(id string) error {
+	return builder.record(nil, nil, "RemoveContainer", id)
+}
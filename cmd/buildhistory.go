@@ -0,0 +1,134 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	buildModeLocal  = "local"
+	buildModeRemote = "remote"
+
+	buildReasonChangedConfig    = "changed build config"
+	buildReasonChangedHash      = "changed input hash"
+	buildReasonForced           = "forced"
+	buildReasonNewLocalCommit   = "new local commit"
+	buildReasonNewCommit        = "new remote commit"
+	buildReasonNoPreviousCommit = "no previous commit"
+	buildReasonNoPreviousHash   = "no previous hash"
+	buildReasonNoLocalPath      = "no local path, building in container"
+	buildReasonNoLocalPathClone = "no local path, building from a temporary clone"
+	buildReasonUnchanged        = "unchanged"
+
+	buildResultCancelled = "cancelled"
+	buildResultFailed    = "failed"
+	buildResultRejected  = "rejected"
+	buildResultSkipped   = "skipped"
+	buildResultSucceeded = "succeeded"
+	buildResultTimedOut  = "timed out"
+
+	rjHistoryFile = ".RJhistory.jsonl"
+	// rootProjectID is the project ID the webserver's builds are recorded under
+	rootProjectID = "root"
+)
+
+// historyLock keeps records of projects built concurrently from interleaving in the history file
+var historyLock sync.Mutex
+
+// appendBuildHistory appends the record to the build history in the project root, which is a file
+// with a JSON encoded record on every line; records are never changed once they are written
+func appendBuildHistory(projectRoot string, record buildRecord) error {
+	recordBytes, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	historyLock.Lock()
+	defer historyLock.Unlock()
+
+	historyFile, err := os.OpenFile(filepath.Join(projectRoot, rjHistoryFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = historyFile.Write(append(recordBytes, '\n')); err != nil {
+		historyFile.Close()
+		return err
+	}
+
+	return historyFile.Close()
+}
+
+// getFailedBuildResult gets the result of a build which returned the error provided, telling builds
+// which were cancelled or timed out apart from ones which failed
+func getFailedBuildResult(err error) string {
+	switch errors.Cause(err).(type) {
+	case *errCommandCancelled:
+		return buildResultCancelled
+	case *errCommandTimedOut:
+		return buildResultTimedOut
+	case *errBuildRejected:
+		return buildResultRejected
+	}
+
+	return buildResultFailed
+}
+
+// readBuildHistory reads every record in the build history of the project root, oldest first
+func readBuildHistory(projectRoot string) ([]buildRecord, error) {
+	records := make([]buildRecord, 0)
+
+	historyFile, err := os.Open(filepath.Join(projectRoot, rjHistoryFile))
+
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer historyFile.Close()
+
+	scanner := bufio.NewScanner(historyFile)
+
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record buildRecord
+
+		// A build interrupted while its record was being written can leave a partial line behind
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
@@ -0,0 +1,215 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	buildLogFooter     = "ROB: build finished"
+	buildLogTailLines  = 20
+	buildLogTimeFormat = "20060102-150405"
+	defaultKeepLogs    = 20
+	rjLogsDir          = ".RJlogs"
+)
+
+// buildLog is a log file of a single build of a project, named '{number}-{timestamp}.log'
+type buildLog struct {
+	Number int
+	Path   string
+}
+
+// getBuildLogsPath gets the directory the build logs of the project are kept in
+func getBuildLogsPath(projectRoot, projectID string) string {
+	return filepath.Join(projectRoot, rjLogsDir, projectID)
+}
+
+// listBuildLogs lists the build logs of the project, oldest first
+func listBuildLogs(projectRoot, projectID string) ([]buildLog, error) {
+	logsPath := getBuildLogsPath(projectRoot, projectID)
+	buildLogs := make([]buildLog, 0)
+
+	logFiles, err := ioutil.ReadDir(logsPath)
+
+	if os.IsNotExist(err) {
+		return buildLogs, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, logFile := range logFiles {
+		if logFile.IsDir() || !strings.HasSuffix(logFile.Name(), ".log") {
+			continue
+		}
+
+		numberAndTime := strings.SplitN(strings.TrimSuffix(logFile.Name(), ".log"), "-", 2)
+
+		if number, err := strconv.Atoi(numberAndTime[0]); err == nil {
+			buildLogs = append(buildLogs, buildLog{Number: number, Path: filepath.Join(logsPath, logFile.Name())})
+		}
+	}
+
+	sort.Slice(buildLogs, func(i, j int) bool {
+		return buildLogs[i].Number < buildLogs[j].Number
+	})
+
+	return buildLogs, nil
+}
+
+// readLogTail reads the last 'lines' lines of the log file
+func readLogTail(logPath string, lines int) ([]string, error) {
+	logFile, err := os.Open(logPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer logFile.Close()
+
+	tail := make([]string, 0, lines)
+	scanner := bufio.NewScanner(logFile)
+
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		if len(tail) == lines {
+			tail = tail[1:]
+		}
+
+		tail = append(tail, scanner.Text())
+	}
+
+	return tail, scanner.Err()
+}
+
+// createBuildLog creates the log file for the next build of the project, returning it with the
+// number of the build
+func createBuildLog(projectRoot, projectID string, startTime time.Time) (*os.File, int, error) {
+	buildLogs, err := listBuildLogs(projectRoot, projectID)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	logsPath := getBuildLogsPath(projectRoot, projectID)
+
+	if err = os.MkdirAll(logsPath, os.ModePerm); err != nil {
+		return nil, 0, err
+	}
+
+	number := 1
+
+	if len(buildLogs) != 0 {
+		number = buildLogs[len(buildLogs)-1].Number + 1
+	}
+
+	logFile, err := os.Create(filepath.Join(logsPath, fmt.Sprintf("%d-%s.log", number, startTime.Local().Format(buildLogTimeFormat))))
+
+	return logFile, number, err
+}
+
+// pruneBuildLogs removes all but the newest 'keepLogs' build logs of the project
+func pruneBuildLogs(projectRoot, projectID string, keepLogs int) {
+	buildLogs, err := listBuildLogs(projectRoot, projectID)
+
+	if err != nil {
+		return
+	}
+
+	for len(buildLogs) > keepLogs {
+		os.Remove(buildLogs[0].Path)
+		buildLogs = buildLogs[1:]
+	}
+}
+
+// rjBuildLogged builds the project with 'rjBuild', writing all of the output of the build to a new
+// build log as well as to 'output' and adding a record of the build to the build history; the tail
+// of the log is printed if the build fails, the log is removed if the build was skipped, and only
+// the newest 'options.keepLogs' logs are kept
+func rjBuildLogged(rjInfo *RJInfo, rjProject RJProject, projectRoot string, options buildOptions, output io.Writer) (bool, error) {
+	record := buildRecord{ProjectID: rjProject.ID, ProjectName: rjProject.Name, Start: time.Now().UTC()}
+	buildOutput := output
+
+	logFile, number, err := createBuildLog(projectRoot, rjProject.ID, record.Start)
+
+	if err != nil {
+		fmt.Fprintf(output, "Problem creating the build log for Project '%s', building without one: %s\n", rjProject.Name, err)
+	} else {
+		fmt.Fprintf(logFile, "ROB: build %d of Project '%s' started at %s\n", number, rjProject.Name, record.Start.Local().Format(time.RFC3339))
+		buildOutput = io.MultiWriter(output, logFile)
+	}
+
+	built, err := rjBuild(rjInfo, rjProject, projectRoot, options, &record, buildOutput)
+
+	record.End = time.Now().UTC()
+	duration := record.End.Sub(record.Start).Round(time.Millisecond)
+
+	switch {
+	case err != nil:
+		record.Error = err.Error()
+		record.Result = getFailedBuildResult(err)
+	case built:
+		record.Result = buildResultSucceeded
+		record.OutputSize, _ = getDirectorySize(filepath.Join(projectRoot, rjProject.SitePath))
+	default:
+		record.Result = buildResultSkipped
+	}
+
+	if logFile != nil {
+		if err != nil {
+			fmt.Fprintf(logFile, "%s at %s after %s: %s: %s\n", buildLogFooter, record.End.Local().Format(time.RFC3339), duration, record.Result, err)
+		} else {
+			fmt.Fprintf(logFile, "%s at %s after %s: %s\n", buildLogFooter, record.End.Local().Format(time.RFC3339), duration, record.Result)
+		}
+
+		logFile.Close()
+
+		if record.Result == buildResultSkipped {
+			os.Remove(logFile.Name())
+		} else {
+			record.LogPath = logFile.Name()
+		}
+
+		if err != nil {
+			if tail, tailErr := readLogTail(logFile.Name(), buildLogTailLines); tailErr == nil {
+				fmt.Fprintf(output, "Build of Project '%s' failed, the last lines of its log (%s) are:\n", rjProject.Name, logFile.Name())
+
+				for _, line := range tail {
+					fmt.Fprintf(output, "    %s\n", line)
+				}
+			}
+		}
+
+		pruneBuildLogs(projectRoot, rjProject.ID, options.keepLogs)
+	}
+
+	if historyErr := appendBuildHistory(projectRoot, record); historyErr != nil {
+		fmt.Fprintf(output, "Problem adding the build of Project '%s' to the build history: %s\n", rjProject.Name, historyErr)
+	}
+
+	return built, err
+}
@@ -0,0 +1,36 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "List, measure or prune the build artifact cache.",
+	Long: `List, measure or prune the build artifact cache.
+Every successful project build is archived into the cache keyed by the project's input hash (or remote commit when built remotely),
+so that building the same inputs again restores the archive instead of running the build.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("cache is not a standalone command")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+}
@@ -0,0 +1,87 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// cacheLsCmd represents the cache ls command
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "Lists the cached build artifacts, optionally only those of the project specified.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cacheDir, err := getCacheDir()
+
+		if err != nil {
+			return err
+		}
+
+		artifacts, err := listArtifacts(cacheDir)
+
+		if err != nil {
+			return err
+		}
+
+		projectNames := getCachedProjectNames()
+		project := strings.TrimSpace(strings.Join(args, " "))
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(writer, "PROJECT\tKEY\tSIZE\tCREATED")
+
+		for _, artifact := range artifacts {
+			projectName, exists := projectNames[artifact.ProjectID]
+
+			if !exists {
+				projectName = artifact.ProjectID
+			}
+
+			if project != "" && project != projectName && project != artifact.ProjectID {
+				continue
+			}
+
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", projectName, artifact.Key, formatSize(artifact.Size), artifact.Created.Format("2006-01-02 15:04:05"))
+		}
+
+		return writer.Flush()
+	},
+}
+
+// getCachedProjectNames maps the IDs of the projects in the project root to their names, the
+// cache is shared between sites so it is fine for the project root to not exist
+func getCachedProjectNames() map[string]string {
+	projectNames := make(map[string]string)
+
+	rjGlobal, err := getRjGlobal(projectRootPath)
+
+	if err != nil {
+		return projectNames
+	}
+
+	for _, rjProject := range rjGlobal.Projects {
+		projectNames[rjProject.ID] = rjProject.Name
+	}
+
+	return projectNames
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheLsCmd)
+}
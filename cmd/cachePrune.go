@@ -0,0 +1,113 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// cachePruneCmd represents the cache prune command
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Removes old build artifacts from the cache.",
+	Long: `Removes old build artifacts from the cache.
+Only the newest '--keepLast' artifacts of each project are kept, along with any artifact newer than '--olderThan' when it is specified;
+the artifacts of the last builds recorded in RJlocal are always kept unless '--all' is specified.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, err := cmd.Flags().GetBool("all")
+
+		if err != nil {
+			return err
+		}
+
+		keepLast, err := cmd.Flags().GetInt("keepLast")
+
+		if err != nil {
+			return err
+		}
+
+		if keepLast < 0 {
+			return errors.New("'--keepLast' can not be negative")
+		}
+
+		olderThan, err := cmd.Flags().GetDuration("olderThan")
+
+		if err != nil {
+			return err
+		}
+
+		cacheDir, err := getCacheDir()
+
+		if err != nil {
+			return err
+		}
+
+		artifacts, err := listArtifacts(cacheDir)
+
+		if err != nil {
+			return err
+		}
+
+		referencedKeys := make(map[string]bool)
+
+		if rjLocal, err := getRjLocal(projectRootPath); err == nil && !all {
+			for projectID, rjLocalProject := range rjLocal.Projects {
+				referencedKeys[projectID+"/"+rjLocalProject.LastBuildHash] = true
+				referencedKeys[projectID+"/"+getArtifactKey(RJRelease{BuildCommit: rjLocalProject.LastBuildCommit, BuildConfig: rjLocalProject.LastBuildConfig})] = true
+			}
+		}
+
+		var removed int
+		var removedSize int64
+		kept := make(map[string]int)
+
+		// Artifacts are listed newest first, so the first 'keepLast' of each project are kept
+		for _, artifact := range artifacts {
+			if !all {
+				if referencedKeys[artifact.ProjectID+"/"+artifact.Key] {
+					continue
+				}
+
+				if kept[artifact.ProjectID] < keepLast || (olderThan != 0 && time.Since(artifact.Created) < olderThan) {
+					kept[artifact.ProjectID]++
+					continue
+				}
+			}
+
+			if err = os.Remove(artifact.Path); err != nil {
+				return errors.Wrapf(err, "problem removing the artifact '%s'", artifact.Path)
+			}
+
+			removed++
+			removedSize += artifact.Size
+		}
+
+		fmt.Printf("Removed %d artifact(s), freeing %s.\n", removed, formatSize(removedSize))
+
+		return nil
+	},
+}
+
+func init() {
+	cachePruneCmd.Flags().Bool("all", false, "Removes every artifact in the cache.")
+	cachePruneCmd.Flags().Int("keepLast", 3, "The number of artifacts to keep for each project, not counting those of the last builds in RJlocal.")
+	cachePruneCmd.Flags().Duration("olderThan", 0, "Only removes artifacts older than this, such as '168h'.")
+	cacheCmd.AddCommand(cachePruneCmd)
+}
@@ -0,0 +1,54 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// cacheSizeCmd represents the cache size command
+var cacheSizeCmd = &cobra.Command{
+	Use:   "size",
+	Short: "Prints the total size of the build artifact cache.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cacheDir, err := getCacheDir()
+
+		if err != nil {
+			return err
+		}
+
+		artifacts, err := listArtifacts(cacheDir)
+
+		if err != nil {
+			return err
+		}
+
+		var totalSize int64
+
+		for _, artifact := range artifacts {
+			totalSize += artifact.Size
+		}
+
+		fmt.Printf("%s in %d artifact(s) at %s\n", formatSize(totalSize), len(artifacts), cacheDir)
+
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheSizeCmd)
+}
@@ -0,0 +1,207 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sizeUnits are the suffixes sizes can be given in, in the order they are checked
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// parseSize parses a size in bytes, such as '1048576', or with a unit which are powers of 1024,
+// such as '500KB', '1.5MB' or '2GiB'
+func parseSize(size string) (int64, error) {
+	number, multiplier := strings.ToUpper(strings.TrimSpace(size)), int64(1)
+
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, multiplier = strings.TrimSpace(strings.TrimSuffix(number, unit.suffix)), unit.multiplier
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+
+	if err != nil || value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("invalid size '%s', expected a number of bytes optionally followed by 'KB', 'MB' or 'GB'", size)
+	}
+
+	// float64(math.MaxInt64) rounds up to 2^63, which doesn't fit in an int64 anymore
+	if value*float64(multiplier) >= float64(math.MaxInt64) {
+		return 0, fmt.Errorf("invalid size '%s', it's too large", size)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
+
+// checkBuildChecks returns an error if any of the project's build checks can't be used
+func checkBuildChecks(checks *RJBuildChecks) error {
+	if checks == nil {
+		return nil
+	}
+
+	for _, glob := range append(append([]string{}, checks.Forbidden...), checks.Required...) {
+		if _, err := path.Match(filepath.ToSlash(glob), ""); err != nil {
+			return fmt.Errorf("invalid check glob '%s'", glob)
+		}
+	}
+
+	if checks.MaxFileSize != "" {
+		if _, err := parseSize(checks.MaxFileSize); err != nil {
+			return errors.Wrap(err, "invalid maximum file size")
+		}
+	}
+
+	if checks.MaxSize != "" {
+		if _, err := parseSize(checks.MaxSize); err != nil {
+			return errors.Wrap(err, "invalid maximum size")
+		}
+	}
+
+	for extension, maxSize := range checks.MaxTypeSizes {
+		if !strings.HasPrefix(extension, ".") {
+			return fmt.Errorf("invalid file type '%s', expected an extension such as '.js'", extension)
+		}
+
+		if _, err := parseSize(maxSize); err != nil {
+			return errors.Wrapf(err, "invalid maximum size of '%s' files", extension)
+		}
+	}
+
+	return nil
+}
+
+// matchCheckGlob checks if the slash-separated path of a file in the build output matches the glob,
+// globs without a '/' are matched against the name of the file in any directory
+func matchCheckGlob(glob, filePath string) bool {
+	glob = filepath.ToSlash(glob)
+
+	if !strings.Contains(glob, "/") {
+		filePath = path.Base(filePath)
+	}
+
+	matched, _ := path.Match(glob, filePath)
+
+	return matched
+}
+
+// runBuildChecks runs the project's build checks against the build output in 'outputPath',
+// returning a description of every check which failed
+func runBuildChecks(checks *RJBuildChecks, outputPath string) ([]string, error) {
+	failures := make([]string, 0)
+
+	if checks == nil {
+		return failures, nil
+	}
+
+	if err := checkBuildChecks(checks); err != nil {
+		return nil, err
+	}
+
+	filePaths, err := listAssets(outputPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, required := range checks.Required {
+		found := false
+
+		for _, filePath := range filePaths {
+			if found = matchCheckGlob(required, filePath); found {
+				break
+			}
+		}
+
+		if !found {
+			failures = append(failures, fmt.Sprintf("required file '%s' is missing", required))
+		}
+	}
+
+	var maxFileSize, totalSize int64
+	typeSizes := make(map[string]int64)
+
+	if checks.MaxFileSize != "" {
+		maxFileSize, _ = parseSize(checks.MaxFileSize)
+	}
+
+	for _, filePath := range filePaths {
+		fileInfo, err := os.Stat(filepath.Join(outputPath, filepath.FromSlash(filePath)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		totalSize += fileInfo.Size()
+		typeSizes[strings.ToLower(path.Ext(filePath))] += fileInfo.Size()
+
+		if checks.MaxFileSize != "" && fileInfo.Size() > maxFileSize {
+			failures = append(failures, fmt.Sprintf("size of '%s' %s is over the maximum file size of %s", filePath, formatSize(fileInfo.Size()), formatSize(maxFileSize)))
+		}
+
+		for _, forbidden := range checks.Forbidden {
+			if matchCheckGlob(forbidden, filePath) {
+				failures = append(failures, fmt.Sprintf("forbidden file '%s' matches '%s'", filePath, forbidden))
+				break
+			}
+		}
+	}
+
+	if checks.MaxSize != "" {
+		if maxSize, _ := parseSize(checks.MaxSize); totalSize > maxSize {
+			failures = append(failures, fmt.Sprintf("total size %s is over the maximum of %s", formatSize(totalSize), formatSize(maxSize)))
+		}
+	}
+
+	extensions := make([]string, 0, len(checks.MaxTypeSizes))
+
+	for extension := range checks.MaxTypeSizes {
+		extensions = append(extensions, extension)
+	}
+
+	sort.Strings(extensions)
+
+	for _, extension := range extensions {
+		if maxSize, _ := parseSize(checks.MaxTypeSizes[extension]); typeSizes[strings.ToLower(extension)] > maxSize {
+			failures = append(failures, fmt.Sprintf("size of '%s' files %s is over the maximum of %s", extension, formatSize(typeSizes[strings.ToLower(extension)]), formatSize(maxSize)))
+		}
+	}
+
+	return failures, nil
+}
@@ -0,0 +1,137 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		size        string
+		expect      int64
+		expectError bool
+	}{
+		{size: "1048576", expect: 1048576},
+		{size: "500KB", expect: 500 << 10},
+		{size: "1.5mb", expect: 3 << 19},
+		{size: " 2 GiB ", expect: 2 << 30},
+		{size: "0", expect: 0},
+		{size: "", expectError: true},
+		{size: "MB", expectError: true},
+		{size: "-1KB", expectError: true},
+		{size: "NaN", expectError: true},
+		{size: "Inf", expectError: true},
+		{size: "-Inf", expectError: true},
+		{size: "1e400", expectError: true},
+		{size: "9223372036854775807", expectError: true},
+		{size: "8589934592GB", expectError: true},
+	}
+
+	for _, testCase := range testCases {
+		size, err := parseSize(testCase.size)
+
+		if testCase.expectError {
+			if err == nil {
+				t.Errorf("expected an error parsing '%s', got %d", testCase.size, size)
+			}
+		} else if err != nil {
+			t.Errorf("unexpected error parsing '%s': %s", testCase.size, err)
+		} else if size != testCase.expect {
+			t.Errorf("expected '%s' to be %d bytes, got %d", testCase.size, testCase.expect, size)
+		}
+	}
+}
+
+func TestRunBuildChecks(t *testing.T) {
+	outputPath, err := ioutil.TempDir("", "rob-checks")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(outputPath)
+
+	files := map[string]int{
+		"index.html":             100,
+		"static/js/main.js":      2000,
+		"static/js/main.js.map":  5000,
+		"static/js/vendor.JS":    1000,
+		"static/css/main.css":    500,
+		"static/media/logo.svg":  300,
+		"static/media/photo.png": 4000,
+	}
+
+	for name, size := range files {
+		filePath := filepath.Join(outputPath, filepath.FromSlash(name))
+
+		if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = ioutil.WriteFile(filePath, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		checks         *RJBuildChecks
+		expectFailures []string
+		expectError    bool
+	}{
+		{name: "no checks", checks: nil},
+		{name: "passing checks", checks: &RJBuildChecks{Forbidden: []string{"*.env"}, MaxFileSize: "5000", MaxSize: "1MB", MaxTypeSizes: map[string]string{".js": "3000"}, Required: []string{"index.html", "static/js/*.js"}}},
+		{name: "missing required file", checks: &RJBuildChecks{Required: []string{"index.html", "manifest.json"}}, expectFailures: []string{"'manifest.json' is missing"}},
+		{name: "forbidden files", checks: &RJBuildChecks{Forbidden: []string{"*.map", "static/media/*.png"}}, expectFailures: []string{"'static/js/main.js.map' matches '*.map'", "'static/media/photo.png' matches 'static/media/*.png'"}},
+		{name: "file over the maximum file size", checks: &RJBuildChecks{MaxFileSize: "4KB"}, expectFailures: []string{"'static/js/main.js.map'"}},
+		{name: "output over the maximum size", checks: &RJBuildChecks{MaxSize: "12KB"}, expectFailures: []string{"total size"}},
+		{name: "type sizes are combined", checks: &RJBuildChecks{MaxTypeSizes: map[string]string{".js": "2500", ".css": "1KB"}}, expectFailures: []string{"'.js' files"}},
+		{name: "invalid size", checks: &RJBuildChecks{MaxSize: "NaN"}, expectError: true},
+		{name: "invalid glob", checks: &RJBuildChecks{Required: []string{"["}}, expectError: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			failures, err := runBuildChecks(testCase.checks, outputPath)
+
+			if testCase.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got the failures %v", failures)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(failures) != len(testCase.expectFailures) {
+				t.Fatalf("expected %d failures, got %v", len(testCase.expectFailures), failures)
+			}
+
+			for index, expectFailure := range testCase.expectFailures {
+				if !strings.Contains(failures[index], expectFailure) {
+					t.Errorf("expected failure %d to contain '%s', got '%s'", index, expectFailure, failures[index])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const (
+	frameworkCRA       = "cra"
+	frameworkNext      = "next"
+	frameworkStatic    = "static"
+	frameworkSvelteKit = "sveltekit"
+	frameworkVite      = "vite"
+	frameworkVueCLI    = "vue-cli"
+
+	packageManagerNpm  = "npm"
+	packageManagerPnpm = "pnpm"
+	packageManagerYarn = "yarn"
+)
+
+// frameworkDefault is the build configuration of a framework which is used unless the project specifies its own
+type frameworkDefault struct {
+	inputDirs   []string
+	nodeVersion string // Empty if the framework builds with the default node version
+	outputDir   string
+}
+
+// frameworkDefaults are the defaults of every framework ROB detects; static sites aren't built,
+// their output directory is copied as it is
+var frameworkDefaults = map[string]frameworkDefault{
+	frameworkCRA:       {inputDirs: []string{"src", "public"}, outputDir: "build"},
+	frameworkNext:      {inputDirs: []string{"app", "components", "lib", "pages", "public", "src", "styles"}, nodeVersion: currentNodeVersion, outputDir: "out"},
+	frameworkStatic:    {outputDir: "."},
+	frameworkSvelteKit: {inputDirs: []string{"src", "static"}, nodeVersion: currentNodeVersion, outputDir: "build"},
+	frameworkVite:      {inputDirs: []string{"src", "public"}, nodeVersion: currentNodeVersion, outputDir: "dist"},
+	frameworkVueCLI:    {inputDirs: []string{"src", "public"}, nodeVersion: currentNodeVersion, outputDir: "dist"},
+}
+
+// frameworkDependencies are the packages frameworks are detected by, in the order they are checked
+// since some frameworks depend on others, such as SvelteKit on Vite
+var frameworkDependencies = []struct {
+	dependency, framework string
+}{
+	{"next", frameworkNext},
+	{"@sveltejs/kit", frameworkSvelteKit},
+	{"@vue/cli-service", frameworkVueCLI},
+	{"vite", frameworkVite},
+	{"react-scripts", frameworkCRA},
+}
+
+// packageManagerLockfiles are the lockfiles package managers are detected by, in the order they are checked
+var packageManagerLockfiles = []struct {
+	lockfile, packageManager string
+}{
+	{"pnpm-lock.yaml", packageManagerPnpm},
+	{"yarn.lock", packageManagerYarn},
+	{"package-lock.json", packageManagerNpm},
+	{"npm-shrinkwrap.json", packageManagerNpm},
+}
+
+// packageManagerInstallCommands are the default install commands of the package managers
+var packageManagerInstallCommands = map[string]string{
+	packageManagerNpm:  defaultInstallCommand,
+	packageManagerPnpm: "pnpm install --frozen-lockfile",
+	packageManagerYarn: "yarn install --frozen-lockfile",
+}
+
+// checkFramework returns an error if the framework isn't one ROB knows
+func checkFramework(framework string) error {
+	if _, exists := frameworkDefaults[framework]; !exists {
+		return fmt.Errorf("unknown framework '%s', expected one of '%s', '%s', '%s', '%s', '%s' or '%s'", framework, frameworkCRA, frameworkNext, frameworkStatic, frameworkSvelteKit, frameworkVite, frameworkVueCLI)
+	}
+
+	return nil
+}
+
+// checkPackageManager returns an error if the package manager isn't one ROB knows
+func checkPackageManager(packageManager string) error {
+	if _, exists := packageManagerInstallCommands[packageManager]; !exists {
+		return fmt.Errorf("unknown package manager '%s', expected one of '%s', '%s' or '%s'", packageManager, packageManagerNpm, packageManagerPnpm, packageManagerYarn)
+	}
+
+	return nil
+}
+
+// detectProject detects the framework and package manager of a project from its package.json and
+// lockfile, which are read with 'readFile'; a project without a package.json but with an index.html
+// is a static site. Nothing is returned for what can't be detected
+func detectProject(readFile func(name string) ([]byte, error)) (string, string, error) {
+	packageBytes, err := readFile("package.json")
+
+	if os.IsNotExist(errors.Cause(err)) {
+		if _, err = readFile("index.html"); err == nil {
+			return frameworkStatic, "", nil
+		}
+
+		return "", "", nil
+	}
+
+	if err != nil {
+		return "", "", errors.Wrap(err, "problem reading package.json")
+	}
+
+	var projectPackage npmPackage
+
+	if err = json.Unmarshal(packageBytes, &projectPackage); err != nil {
+		return "", "", errors.Wrap(err, "problem decoding package.json")
+	}
+
+	var framework string
+
+	for _, frameworkDependency := range frameworkDependencies {
+		_, dependency := projectPackage.Dependencies[frameworkDependency.dependency]
+		_, devDependency := projectPackage.DevDependencies[frameworkDependency.dependency]
+
+		if dependency || devDependency {
+			framework = frameworkDependency.framework
+			break
+		}
+	}
+
+	// A package.json can be only for tooling, such as linting, in which case a site which isn't
+	// built is still static
+	if _, hasBuildScript := projectPackage.Scripts[defaultBuildScript]; framework == "" && !hasBuildScript {
+		if _, err = readFile("index.html"); err == nil {
+			return frameworkStatic, "", nil
+		}
+	}
+
+	packageManager := packageManagerNpm
+
+	for _, packageManagerLockfile := range packageManagerLockfiles {
+		if _, err = readFile(packageManagerLockfile.lockfile); err == nil {
+			packageManager = packageManagerLockfile.packageManager
+			break
+		}
+	}
+
+	return framework, packageManager, nil
+}
+
+// detectLocalProject detects the framework and package manager of the project at 'projectPath', see 'detectProject'
+func detectLocalProject(projectPath string) (string, string, error) {
+	return detectProject(func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.Join(projectPath, name))
+	})
+}
+
+// detectRemoteProject detects the framework and package manager of the project from the files of
+// the commit 'ref' resolves to in its remote repository, see 'detectProject'
+func detectRemoteProject(ctx context.Context, projectURL, ref string) (string, string, error) {
+	commitHash, err := resolveRemoteRef(ctx, projectURL, ref)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	repository, err := cloneRemoteProjectToMemory(ctx, projectURL)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	commit, err := repository.CommitObject(commitHash)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return detectProject(func(name string) ([]byte, error) {
+		file, err := commit.File(name)
+
+		if err == object.ErrFileNotFound {
+			return nil, os.ErrNotExist
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := file.Contents()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(contents), nil
+	})
+}
+
+// applyDetectedProject fills in the framework and package manager of the project from the project
+// at 'projectPath' if it doesn't specify them, which is how projects added before detection or
+// without anything detected get built with the right defaults
+func applyDetectedProject(rjProject RJProject, projectPath string) RJProject {
+	if rjProject.Framework != "" && rjProject.PackageManager != "" {
+		return rjProject
+	}
+
+	framework, packageManager, err := detectLocalProject(projectPath)
+
+	if err != nil {
+		return rjProject
+	}
+
+	if rjProject.Framework == "" {
+		rjProject.Framework = framework
+	}
+
+	if rjProject.PackageManager == "" {
+		rjProject.PackageManager = packageManager
+	}
+
+	return rjProject
+}
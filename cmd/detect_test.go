@@ -0,0 +1,131 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDetectProject(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		files                map[string]string
+		expectFramework      string
+		expectPackageManager string
+	}{
+		{
+			name:                 "create react app with npm",
+			files:                map[string]string{"package.json": `{"dependencies": {"react-scripts": "5.0.1"}, "scripts": {"build": "react-scripts build"}}`, "package-lock.json": "{}"},
+			expectFramework:      frameworkCRA,
+			expectPackageManager: packageManagerNpm,
+		},
+		{
+			name:                 "next with yarn",
+			files:                map[string]string{"package.json": `{"dependencies": {"next": "14.0.0", "react": "18.2.0"}}`, "yarn.lock": ""},
+			expectFramework:      frameworkNext,
+			expectPackageManager: packageManagerYarn,
+		},
+		{
+			name:                 "sveltekit over vite with pnpm",
+			files:                map[string]string{"package.json": `{"devDependencies": {"@sveltejs/kit": "2.0.0", "vite": "5.0.0"}}`, "pnpm-lock.yaml": ""},
+			expectFramework:      frameworkSvelteKit,
+			expectPackageManager: packageManagerPnpm,
+		},
+		{
+			name:                 "vite with a shrinkwrap",
+			files:                map[string]string{"package.json": `{"devDependencies": {"vite": "5.0.0"}}`, "npm-shrinkwrap.json": "{}"},
+			expectFramework:      frameworkVite,
+			expectPackageManager: packageManagerNpm,
+		},
+		{
+			name:                 "vue cli over vite",
+			files:                map[string]string{"package.json": `{"devDependencies": {"@vue/cli-service": "5.0.0", "vite": "5.0.0"}}`},
+			expectFramework:      frameworkVueCLI,
+			expectPackageManager: packageManagerNpm,
+		},
+		{
+			name:                 "pnpm over other lockfiles",
+			files:                map[string]string{"package.json": `{"devDependencies": {"vite": "5.0.0"}}`, "pnpm-lock.yaml": "", "package-lock.json": "{}"},
+			expectFramework:      frameworkVite,
+			expectPackageManager: packageManagerPnpm,
+		},
+		{
+			name:                 "unknown framework with a build script",
+			files:                map[string]string{"package.json": `{"scripts": {"build": "make"}}`, "index.html": ""},
+			expectPackageManager: packageManagerNpm,
+		},
+		{
+			name:            "static site with a tooling package.json",
+			files:           map[string]string{"package.json": `{"devDependencies": {"eslint": "8.0.0"}}`, "index.html": ""},
+			expectFramework: frameworkStatic,
+		},
+		{
+			name:            "static site",
+			files:           map[string]string{"index.html": ""},
+			expectFramework: frameworkStatic,
+		},
+		{
+			name:  "nothing to detect",
+			files: map[string]string{"README.md": ""},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			framework, packageManager, err := detectProject(func(name string) ([]byte, error) {
+				if contents, exists := testCase.files[name]; exists {
+					return []byte(contents), nil
+				}
+
+				return nil, os.ErrNotExist
+			})
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if framework != testCase.expectFramework {
+				t.Errorf("expected the framework '%s', got '%s'", testCase.expectFramework, framework)
+			}
+
+			if packageManager != testCase.expectPackageManager {
+				t.Errorf("expected the package manager '%s', got '%s'", testCase.expectPackageManager, packageManager)
+			}
+		})
+	}
+}
+
+func TestGetReactBuildConfigNodeVersion(t *testing.T) {
+	testCases := []struct {
+		project           RJProject
+		expectNodeVersion string
+	}{
+		{RJProject{}, defaultNodeVersion},
+		{RJProject{Framework: frameworkCRA, PackageManager: packageManagerYarn}, defaultNodeVersion},
+		{RJProject{Framework: frameworkCRA, PackageManager: packageManagerPnpm}, currentNodeVersion},
+		{RJProject{Framework: frameworkNext}, currentNodeVersion},
+		{RJProject{Framework: frameworkSvelteKit}, currentNodeVersion},
+		{RJProject{Framework: frameworkVite}, currentNodeVersion},
+		{RJProject{Framework: frameworkVueCLI}, currentNodeVersion},
+		{RJProject{Framework: frameworkVite, NodeVersion: "18-alpine"}, "18-alpine"},
+	}
+
+	for _, testCase := range testCases {
+		if nodeVersion := getReactBuildConfig(testCase.project).NodeVersion; nodeVersion != testCase.expectNodeVersion {
+			t.Errorf("expected the node version '%s' for %+v, got '%s'", testCase.expectNodeVersion, testCase.project, nodeVersion)
+		}
+	}
+}
@@ -40,12 +40,14 @@ to quickly create a Cobra application.`,
 			return err
 		}
 
-		rjInfo, err := getRjInfo(projectRootPath)
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
 
 		if err != nil {
 			return err
 		}
 
+		defer lock.Unlock()
+
 		if len(rjInfo.RJLocal.SearchPaths) != 0 {
 			discoveredTagPaths := make([]string, 0)
 
@@ -1,42 +1,47 @@
 package cmd
 
+// localReactBuild is rendered with a reactBuildConfig, every root level config file is copied
+// ahead of the install so that the dependency layer stays cached until they change
 const localReactBuild string = `
-FROM node:8.11.3-alpine
+FROM node:{{.NodeVersion}}
 
-WORKDIR /app
-
-COPY tslint.json .
-COPY tsconfig.test.json .
-COPY tsconfig.prod.json .
-COPY tsconfig.json .
-COPY package-lock.json .
-COPY images.d.ts .
-COPY package.json .
-
-RUN npm install
-ADD ./src ./src
-ADD ./public ./public
-
-ENTRYPOINT ["npm", "run", "build"]`
+LABEL rob.managed=true
 
+WORKDIR /app
+{{range $key, $value := .BuildEnv}}ENV {{$key}}={{envQuote $value}}
+{{end}}
+COPY *.json *.js *.cjs *.mjs *.ts *.html *.lock *.yaml ./
+
+{{if eq .PackageManager "pnpm"}}RUN corepack enable
+{{end}}RUN {{.InstallCommand}}
+{{range .InputDirs}}ADD ./{{.}} ./{{.}}
+{{end}}
+ENTRYPOINT [{{quote .PackageManager}}, "run", {{quote .BuildScript}}{{range .ScriptArgs}}, {{quote .}}{{end}}]`
+
+// remoteReactBuild is rendered with a reactBuildConfig, the build context is a clean checkout of the
+// remote commit so all of it is copied, after the install like the local template's input directories
 const remoteReactBuild string = `
-FROM node:8.11.3-alpine
+FROM node:{{.NodeVersion}}
 
-ARG GITHUB_URL
-ARG GITHUB_DIR
-
-ADD ${GITHUB_URL}/archive/master.tar.gz ./
-RUN tar -xzf master.tar.gz -C ./ && mv ./${GITHUB_DIR}-master app
+LABEL rob.managed=true
 
 WORKDIR /app
+{{range $key, $value := .BuildEnv}}ENV {{$key}}={{envQuote $value}}
+{{end}}
+COPY *.json *.js *.cjs *.mjs *.ts *.html *.lock *.yaml ./
+
+{{if eq .PackageManager "pnpm"}}RUN corepack enable
+{{end}}RUN {{.InstallCommand}}
 
-RUN npm install
+COPY . ./
 
-ENTRYPOINT ["npm", "run", "build"]`
+ENTRYPOINT [{{quote .PackageManager}}, "run", {{quote .BuildScript}}{{range .ScriptArgs}}, {{quote .}}{{end}}]`
 
 const robInstallBuilderLocal string = `
 FROM golang:1.10.3-alpine3.8
 
+LABEL rob.managed=true
+
 WORKDIR /go/src/github.com/the-rileyj/rob
 
 RUN apk update && \
@@ -61,6 +66,8 @@ ENTRYPOINT cat ./bin/rob`
 const robInstallBuilderRemote string = `
 FROM golang:1.10.3-alpine3.8
 
+LABEL rob.managed=true
+
 WORKDIR /go/src/github.com/the-rileyj
 
 RUN apk update && \
@@ -85,24 +92,33 @@ WORKDIR /
 
 ENTRYPOINT cat ./bin/rob`
 
+// rootBuild is rendered with a rootDockerfileData, the target platform is also passed as build
+// arguments; the modules are downloaded ahead of copying the rest of the module, and the build
+// arguments are only declared after that, so that the layers stay cached across targets until
+// go.mod or go.sum change
 const rootBuild string = `
-FROM golang:1.10.3-alpine3.8
+FROM golang:{{.GoVersion}}
 
-ARG BUILD_NAME
-ARG GOARCH
-ARG GOOS
+LABEL rob.managed=true
 
 WORKDIR /app
+{{if .CGOEnabled}}
+RUN apk add --no-cache gcc musl-dev
+{{end}}
+COPY go.mod go.sum* ./
+RUN go mod download
 
-RUN mkdir out && mkdir dst && apk update && apk upgrade && apk add --no-cache gcc git musl-dev
+COPY . ./
 
-ADD *.go .
+ARG BUILD_NAME
+ARG GOARCH
+ARG GOARM
+ARG GOOS
 
-RUN go get -d ./...
-RUN env CGO_ENABLED=0 GOOS=${GOOS} GOARCH=${GOARCH} \
-	go build --ldflags '-extldflags "-static"' \
-	-o ./out/${BUILD_NAME}
+RUN env CGO_ENABLED={{if .CGOEnabled}}1{{else}}0{{end}} GOOS=${GOOS} GOARCH=${GOARCH} GOARM=${GOARM} \
+	go build -buildvcs=false -trimpath{{if .Tags}} -tags {{shellQuote .Tags}}{{end}} -ldflags {{shellQuote .LDFlags}} \
+	-o /out/${BUILD_NAME} .
 
 ENV BUILD_NAME=${BUILD_NAME}
 
-ENTRYPOINT cat ./out/${BUILD_NAME}`
+ENTRYPOINT cat /out/${BUILD_NAME}`
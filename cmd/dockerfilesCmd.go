@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// dockerfilesCmd represents the dockerfiles command
+var dockerfilesCmd = &cobra.Command{
+	Use:   "dockerfiles",
+	Short: "Export the default Dockerfile templates so they can be customized.",
+	Long: `Export the default Dockerfile templates so they can be customized.
+The Dockerfiles ROB builds with are Go text/template templates; a project is built with the template at its 'dockerfile' path if it has one,
+otherwise with '` + reactLocalDockerfile + `' or '` + reactRemoteDockerfile + `' in the project root if it exists, otherwise with the default.
+The webserver is built with '` + rootDockerfile + `' in the project root if it exists, otherwise with the default.
+A project's rendered Dockerfile is part of its hash and artifact key, so changing the template it's built with rebuilds it (see 'hash').
+The default templates label their layers with 'LABEL rob.managed=true' so that 'gc' finds the layers of failed builds, customized templates should keep it.
+Project templates are rendered with the project's build configuration (.Framework, .PackageManager, .NodeVersion, .InstallCommand, .BuildScript, .ScriptArgs, .OutputDir, .InputDirs, .BuildEnv, .BaseURL),
+the project itself (.Project) and whether it's built from a clone of its remote (.Remote); the webserver template with .BuildName, .GoArch, .GoARM, .GoOS, .GoVersion, .CGOEnabled, .Tags and .LDFlags.
+Templates can quote strings in JSON form with 'quote', for ENV and ARG instructions with 'envQuote' and for shell commands with 'shellQuote'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("dockerfiles is not a standalone command")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dockerfilesCmd)
+}
@@ -0,0 +1,83 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// dockerfilesExportCmd represents the dockerfiles export command
+var dockerfilesExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Writes the default Dockerfile templates to the project root, or '--dir', to be customized.",
+	Long: `Writes the default Dockerfile templates to the project root, or '--dir', to be customized.
+Templates written to the project root are used instead of the defaults straight away; existing templates are only overwritten if '--force' is specified.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := cmd.Flags().GetString("dir")
+
+		if err != nil {
+			return err
+		}
+
+		force, err := cmd.Flags().GetBool("force")
+
+		if err != nil {
+			return err
+		}
+
+		if dir == "" {
+			dir = projectRootPath
+		}
+
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return errors.Wrapf(err, "problem creating '%s'", dir)
+		}
+
+		for _, dockerfile := range []struct {
+			name, template string
+		}{
+			{reactLocalDockerfile, localReactBuild},
+			{reactRemoteDockerfile, remoteReactBuild},
+			{rootDockerfile, rootBuild},
+		} {
+			dockerfilePath := filepath.Join(dir, dockerfile.name)
+
+			if _, err = os.Stat(dockerfilePath); err == nil && !force {
+				fmt.Printf("Skipping %s because it already exists, specify '--force' to overwrite it.\n", dockerfilePath)
+				continue
+			}
+
+			if err = writeFileSynced(dockerfilePath, []byte(strings.TrimPrefix(dockerfile.template, "\n")+"\n")); err != nil {
+				return errors.Wrapf(err, "problem writing '%s'", dockerfilePath)
+			}
+
+			fmt.Printf("Wrote %s\n", dockerfilePath)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	dockerfilesExportCmd.Flags().String("dir", "", "The directory the templates are written to (default the project root).")
+	dockerfilesExportCmd.Flags().BoolP("force", "f", false, "Overwrites templates which already exist.")
+	dockerfilesCmd.AddCommand(dockerfilesExportCmd)
+}
@@ -16,6 +16,8 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"time"
 )
 
 type errRjFileNotFound struct {
@@ -34,3 +36,81 @@ func (err errRjFileNotFound) Error() string {
 func newErrRjFileNotFound(rjGlobal, rjLocal bool) error {
 	return &errRjFileNotFound{rjGlobal, rjLocal}
 }
+
+type errWorkspaceBusy struct {
+	RootPath string
+	Timeout  time.Duration
+}
+
+func (err errWorkspaceBusy) Error() string {
+	if err.Timeout > 0 {
+		return fmt.Sprintf("workspace busy: another ROB instance is still updating %s after waiting %s, try again later or raise '--lockTimeout'", err.RootPath, err.Timeout)
+	}
+	return fmt.Sprintf("workspace busy: another ROB instance is updating %s, try again later or specify '--lockTimeout' to wait for it", err.RootPath)
+}
+
+func newErrWorkspaceBusy(rootPath string, timeout time.Duration) error {
+	return &errWorkspaceBusy{rootPath, timeout}
+}
+
+type errRjSchemaTooNew struct {
+	FileName               string
+	Version, LatestVersion int
+}
+
+func (err errRjSchemaTooNew) Error() string {
+	return fmt.Sprintf("%s is at schema version %d but this version of ROB only understands up to version %d, please upgrade ROB", err.FileName, err.Version, err.LatestVersion)
+}
+
+func newErrRjSchemaTooNew(fileName string, version, latestVersion int) error {
+	return &errRjSchemaTooNew{fileName, version, latestVersion}
+}
+
+type errCommandCancelled struct {
+	Command string
+}
+
+func (err errCommandCancelled) Error() string {
+	return fmt.Sprintf("'%s' was cancelled", err.Command)
+}
+
+func newErrCommandCancelled(command string) error {
+	return &errCommandCancelled{command}
+}
+
+type errCommandTimedOut struct {
+	Command string
+}
+
+func (err errCommandTimedOut) Error() string {
+	return fmt.Sprintf("'%s' timed out, raise '--timeout' if it needs longer", err.Command)
+}
+
+func newErrCommandTimedOut(command string) error {
+	return &errCommandTimedOut{command}
+}
+
+type errCommandExitCode struct {
+	Command  string
+	ExitCode int
+}
+
+func (err errCommandExitCode) Error() string {
+	return fmt.Sprintf("'%s' exited with code %d", err.Command, err.ExitCode)
+}
+
+func newErrCommandExitCode(command string, exitCode int) error {
+	return &errCommandExitCode{command, exitCode}
+}
+
+type errBuildRejected struct {
+	Failures []string
+}
+
+func (err errBuildRejected) Error() string {
+	return fmt.Sprintf("the build output failed %d check(s): %s", len(err.Failures), strings.Join(err.Failures, "; "))
+}
+
+func newErrBuildRejected(failures []string) error {
+	return &errBuildRejected{failures}
+}
@@ -16,229 +16,510 @@ package cmd
 
 import (
 	"bytes"
-	"crypto/sha1"
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
-	"os/signal"
 	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"syscall"
+	"sync"
+	"text/tabwriter"
+	"text/template"
 	"time"
 
 	"github.com/pkg/errors"
 	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/gitignore"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
 	"gopkg.in/src-d/go-git.v4/storage/memory"
 )
 
-func buildProject(localPath, rootPath, sitePath, githubURL string, remote bool) (string, error) {
-	newHash := ""
+// buildProject builds the project into 'stagingPath', which must be an absolute path; the project
+// is built from 'localPath' unless a remote commit to build is provided, in which case the commit
+// is cloned to a temporary directory on the host and built from there. The clone, install and
+// build phases are retried according to the project's retry policy, see 'runPhase'
+func buildProject(rjProject RJProject, localPath, stagingPath, remoteCommit string, options buildOptions, record *buildRecord, output io.Writer) error {
+	remote := remoteCommit != ""
 
-	if !remote {
-		newHash = dasher(localPath, -1)
-	}
-
-	absRoot, err := filepath.Abs(rootPath)
+	policy, err := getRetryPolicy(rjProject, options)
 
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	var imageBuildArgs []string
 	if remote {
-		// Equivalent to:
-		// docker build -t rjtest:latest \
-		// --build-arg GITHUB_DIR={Project Name} --build-arg GITHUB_URL={Project Github URL} \
-		// -f - {path to build context}
-		imageBuildArgs = []string{
-			"build", "-t", "rj-react-build:latest",
-			"--build-arg", fmt.Sprintf("GITHUB_DIR=%s", path.Base(githubURL)),
-			"--build-arg", fmt.Sprintf("GITHUB_URL=%s", githubURL),
-			"-f", "-", filepath.Clean(localPath),
+		cloneDir, err := ioutil.TempDir("", fmt.Sprintf("rob-%s-", rjProject.ID))
+
+		if err != nil {
+			return err
 		}
-	} else {
-		// Equivalent to "docker build -t rj-react-build:latest -f - {path to build context}"
-		imageBuildArgs = []string{
-			"build", "-t", "rj-react-build:latest",
-			"-f", "-",
-			filepath.Clean(localPath),
+
+		defer os.RemoveAll(cloneDir)
+
+		fmt.Fprintf(output, "Cloning commit '%s' of Project '%s' to %s.\n", remoteCommit, rjProject.Name, cloneDir)
+
+		err = runPhase(options.ctx, policy, retryPhaseClone, record, output, func() error {
+			// A failed clone can leave a partial repository behind which the next attempt can't clone into
+			if err := removeContents(cloneDir); err != nil {
+				return err
+			}
+
+			return cloneRemoteProject(options.ctx, rjProject, remoteCommit, cloneDir)
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "problem cloning Project '%s'", rjProject.Name)
 		}
+
+		localPath = cloneDir
+	}
+
+	rjProject, buildConfig := resolveBuildConfig(rjProject, localPath, options.siteURL, remote)
+
+	if buildConfig.Framework == frameworkStatic {
+		fmt.Fprintf(output, "Project '%s' is a static site, copying '%s' without building it.\n", rjProject.Name, buildConfig.OutputDir)
+
+		return copyDirectorySkipping(filepath.Join(localPath, filepath.FromSlash(buildConfig.OutputDir)), stagingPath, map[string]bool{
+			".git":         true,
+			".RJtag":       true,
+			"node_modules": true,
+		})
+	}
+
+	fmt.Fprintf(output, "Building Project '%s' to be served under '%s'.\n", rjProject.Name, buildConfig.BaseURL)
+
+	if options.native {
+		return buildProjectNatively(options.ctx, buildConfig, localPath, stagingPath, policy, record, output)
 	}
 
-	cmd := exec.Command("docker", imageBuildArgs...)
+	buildImage := getReactBuildImage(rjProject)
+
+	dockerfile, err := renderReactDockerfile(rjProject, buildConfig, options.projectRoot, localPath, remote)
+
+	if err != nil {
+		return err
+	}
 
 	if remote {
-		cmd.Stdin = bytes.NewBufferString(remoteReactBuild)
-	} else {
-		cmd.Stdin = bytes.NewBufferString(localReactBuild)
+		// The history isn't needed in the container and would only slow down sending the build context
+		if err = os.RemoveAll(filepath.Join(localPath, ".git")); err != nil {
+			return err
+		}
 	}
 
-	cmd.Stdout = os.Stdout
+	// The dependencies are installed while building the image
+	err = runPhase(options.ctx, policy, retryPhaseInstall, record, output, func() error {
+		return options.builder.BuildImage(options.ctx, imageBuildOptions{
+			ContextDir: localPath,
+			Dockerfile: dockerfile,
+			Image:      buildImage,
+			Labels:     getRobLabels(buildImage, rjProject.ID),
+			Stderr:     output,
+			Stdout:     output,
+		})
+	})
 
-	killChannel := make(chan os.Signal, 1)
+	if err != nil {
+		return err
+	}
 
-	signal.Notify(killChannel,
-		syscall.SIGHUP,
-		syscall.SIGINT,
-		syscall.SIGTERM,
-		syscall.SIGQUIT,
-	)
+	return runPhase(options.ctx, policy, retryPhaseBuild, record, output, func() error {
+		return options.builder.Run(options.ctx, containerRunOptions{
+			CopyOut: map[string]string{
+				fmt.Sprintf("/app/%s", buildConfig.OutputDir): stagingPath,
+			},
+			Image:  buildImage,
+			Labels: getRobLabels(buildImage, rjProject.ID),
+			Name:   generateID(),
+			Stderr: output,
+			Stdout: output,
+		})
+	})
+}
+
+func buildProjectLocally(rjProject RJProject, localPath, stagingPath string, options buildOptions, record *buildRecord, output io.Writer) error {
+	return buildProject(rjProject, localPath, stagingPath, "", options, record, output)
+}
 
-	err = cmd.Start()
+// buildProjectNatively builds the project with the node installation on the host instead of in a
+// container
+func buildProjectNatively(ctx context.Context, buildConfig reactBuildConfig, localPath, stagingPath string, policy retryPolicy, record *buildRecord, output io.Writer) error {
+	buildEnv := os.Environ()
+
+	for key, value := range buildConfig.BuildEnv {
+		buildEnv = append(buildEnv, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	err := runPhase(ctx, policy, retryPhaseInstall, record, output, func() error {
+		return runShellCommand(ctx, buildConfig.InstallCommand, localPath, buildEnv, output)
+	})
 
 	if err != nil {
-		return "", err
+		return errors.Wrapf(err, "problem installing the dependencies with '%s'", buildConfig.InstallCommand)
 	}
 
-	go manageProcessReaping(cmd, killChannel)
+	err = runPhase(ctx, policy, retryPhaseBuild, record, output, func() error {
+		cmd := exec.Command(buildConfig.PackageManager, append([]string{"run", buildConfig.BuildScript}, buildConfig.ScriptArgs...)...)
 
-	err = cmd.Wait()
+		cmd.Dir = localPath
+		cmd.Env = buildEnv
+		cmd.Stdout = output
+		cmd.Stderr = output
 
-	// Indicates that 'manageProcessReaping' can exit
-	killChannel <- RJSignal{}
+		return runCommand(ctx, cmd, nil)
+	})
 
 	if err != nil {
-		return "", err
+		return errors.Wrapf(err, "problem running the '%s' script", buildConfig.BuildScript)
+	}
+
+	if err := copyDirectory(filepath.Join(localPath, filepath.FromSlash(buildConfig.OutputDir)), stagingPath); err != nil {
+		return errors.Wrap(err, "problem copying the build output to the staging directory")
+	}
+
+	return nil
+}
+
+func buildProjectRemotely(rjProject RJProject, remoteCommit, stagingPath string, options buildOptions, record *buildRecord, output io.Writer) error {
+	return buildProject(rjProject, "", stagingPath, remoteCommit, options, record, output)
+}
+
+// buildRoot builds the webserver in a container and outputs it to the working directory, returning
+// the paths of the executables; it's built for this machine's platform unless targets are given, in
+// which case an executable named after its platform is built for each along with a checksum file.
+// The version and commit of the project root are stamped into 'main.version' and 'main.commit'
+func buildRoot(ctx context.Context, rootPath string, builder Builder, targets []buildTarget, rootBuild *RJRootBuild) ([]string, error) {
+	if err := checkRootBuildTargets(rootBuild, targets); err != nil {
+		return nil, err
 	}
 
-	dockerBuildName := generateID()
+	data, err := getRootDockerfileData(rootPath, rootBuild)
 
-	// Equivalent to "run --rm -v "{local RJsite path}/{project site path}:/app/build" rj-react-build:latest"
-	runBuildArgs := []string{
-		"run", "--rm",
-		"-v", fmt.Sprintf(`%s:/app/build`, filepath.Clean(path.Join(absRoot, sitePath))),
-		"--name", dockerBuildName,
-		"rj-react-build:latest",
+	if err != nil {
+		return nil, err
 	}
 
-	cmd = exec.Command("docker", runBuildArgs...)
+	if len(targets) == 0 {
+		executablePath, err := buildRootTarget(ctx, rootPath, builder, data, buildTarget{GoArch: runtime.GOARCH, GoOS: runtime.GOOS}, rjServer, "rj-root-build:latest")
 
-	cmd.Stdout = os.Stdout
+		return []string{executablePath}, err
+	}
+
+	executablePaths := make([]string, 0, len(targets))
 
-	err = cmd.Start()
+	for _, target := range targets {
+		fmt.Printf("Building %s for %s.\n", rjServer, target)
+
+		executablePath, err := buildRootTarget(ctx, rootPath, builder, data, target, fmt.Sprintf("%s-%s", rjServer, target.suffix()), "rj-root-build:"+target.suffix())
+
+		if err != nil {
+			return executablePaths, errors.Wrapf(err, "problem building %s for %s", rjServer, target)
+		}
+
+		executablePaths = append(executablePaths, executablePath)
+	}
+
+	if err := writeChecksums(rjServer+".sha256", executablePaths); err != nil {
+		return executablePaths, errors.Wrap(err, "problem writing the checksums of the executables")
+	}
+
+	return executablePaths, nil
+}
+
+// buildRootTarget builds the webserver for a single target in a container and outputs it to the
+// working directory as 'buildName', with '.exe' appended for Windows, returning its path
+func buildRootTarget(ctx context.Context, rootPath string, builder Builder, data rootDockerfileData, target buildTarget, buildName, image string) (string, error) {
+	if target.GoOS == "windows" {
+		buildName += ".exe"
+	}
+
+	data.BuildName, data.GoARM, data.GoArch, data.GoOS = buildName, target.GoARM, target.GoArch, target.GoOS
+
+	dockerfile, err := renderRootDockerfile(rootPath, data)
 
 	if err != nil {
 		return "", err
 	}
 
-	go manageRunReaping(dockerBuildName, killChannel)
+	err = builder.BuildImage(ctx, imageBuildOptions{
+		BuildArgs: map[string]string{
+			"BUILD_NAME": buildName,
+			"GOARCH":     target.GoArch,
+			"GOARM":      target.GoARM,
+			"GOOS":       target.GoOS,
+		},
+		ContextDir: rootPath,
+		Dockerfile: dockerfile,
+		Image:      image,
+		Labels:     getRobLabels(image, rootProjectID),
+		Stderr:     os.Stderr,
+		Stdout:     os.Stdout,
+	})
 
-	err = cmd.Wait()
+	if err != nil {
+		return "", err
+	}
 
-	// Indicates that 'manageProcessReaping' can exit
-	killChannel <- RJSignal{}
+	serverExecutable, err := os.Create(buildName)
 
-	return newHash, err
-}
+	if err != nil {
+		return "", err
+	}
 
-func buildProjectLocally(localPath, rootPath, sitePath string) (string, error) {
-	return buildProject(localPath, rootPath, sitePath, "", false)
-}
+	defer serverExecutable.Close()
 
-func buildProjectRemotely(rootPath, sitePath, githubURL string) error {
-	_, err := buildProject("", rootPath, sitePath, githubURL, true)
-	return err
+	return buildName, builder.Run(ctx, containerRunOptions{
+		Image:  image,
+		Labels: getRobLabels(image, rootProjectID),
+		Name:   generateID(),
+		Stderr: os.Stderr,
+		Stdout: serverExecutable,
+	})
 }
 
-// buildRoot builds the webserver in a docker container and outputs it to the project root
-func buildRoot(rootPath string) error {
-	buildName, goArch, goOS := rjServer, runtime.GOARCH, runtime.GOOS
+// getRootDockerfileData gets what the webserver's Dockerfile template is rendered with, other than
+// the target platform, from how the webserver is configured to be built and the project root's version
+func getRootDockerfileData(rootPath string, rootBuild *RJRootBuild) (rootDockerfileData, error) {
+	data := rootDockerfileData{GoVersion: defaultGoVersion}
 
-	if goOS == "windows" {
-		buildName += ".exe"
+	if err := checkRootBuild(rootBuild); err != nil {
+		return data, err
 	}
 
-	// Equivalent to "build -t rj-root-build:latest --build-arg GOOS=windows --build-arg GOARCH=amd64 --build-arg BUILD_NAME=rjtest -f -""
-	runRootBuildArgs := []string{
-		"build", "-t", "rj-root-build:latest",
-		"--build-arg", fmt.Sprintf("BUILD_NAME=%s", buildName),
-		"--build-arg", fmt.Sprintf("GOARCH=%s", goArch),
-		"--build-arg", fmt.Sprintf("GOOS=%s", goOS),
-		"-f", "-", rootPath,
+	version, commit, err := getRootVersion(rootPath)
+
+	if err != nil {
+		return data, errors.Wrap(err, "problem getting the version of the project root")
 	}
 
-	cmd := exec.Command("docker", runRootBuildArgs...)
+	ldFlags := []string{fmt.Sprintf("-X main.version=%s -X main.commit=%s", version, commit)}
 
-	cmd.Stdin = bytes.NewBufferString(rootBuild)
+	if rootBuild != nil {
+		data.CGOEnabled, data.Tags = rootBuild.CGO, strings.Join(rootBuild.Tags, ",")
 
-	cmd.Stdout = os.Stdout
+		if rootBuild.GoVersion != "" {
+			data.GoVersion = rootBuild.GoVersion
+		}
 
-	killChannel := make(chan os.Signal, 1)
+		// cgo links against musl in the image, which is only portable when it's linked statically
+		if rootBuild.CGO {
+			ldFlags = append(ldFlags, `-extldflags "-static"`)
+		}
 
-	signal.Notify(killChannel,
-		syscall.SIGHUP,
-		syscall.SIGINT,
-		syscall.SIGTERM,
-		syscall.SIGQUIT,
-	)
+		if rootBuild.LDFlags != "" {
+			ldFlags = append(ldFlags, rootBuild.LDFlags)
+		}
+	}
 
-	err := cmd.Start()
+	data.LDFlags = strings.Join(ldFlags, " ")
 
-	if err != nil {
-		return err
+	return data, nil
+}
+
+// checkRootBuild returns an error if the webserver can't be built with the configuration
+func checkRootBuild(rootBuild *RJRootBuild) error {
+	if rootBuild == nil {
+		return nil
+	}
+
+	if strings.ContainsAny(rootBuild.GoVersion, " \t\n:/") {
+		return fmt.Errorf("invalid Go version '%s', expected the tag of a golang image such as '%s'", rootBuild.GoVersion, defaultGoVersion)
+	}
+
+	// The default template builds with '-buildvcs', which Go 1.18 added, and installs the C toolchain
+	// for cgo with apk, which only the alpine images have; tags without a version such as 'alpine' are the latest
+	var major, minor int
+
+	if parsed, _ := fmt.Sscanf(rootBuild.GoVersion, "%d.%d", &major, &minor); parsed == 2 && (major < 1 || major == 1 && minor < 18) {
+		return fmt.Errorf("Go version '%s' is too old, the webserver is built with Go 1.18 or newer", rootBuild.GoVersion)
+	}
+
+	if rootBuild.CGO && rootBuild.GoVersion != "" && !strings.Contains(rootBuild.GoVersion, "alpine") {
+		return fmt.Errorf("Go version '%s' is not an alpine image, which cgo builds need to install gcc with apk", rootBuild.GoVersion)
+	}
+
+	for _, tag := range rootBuild.Tags {
+		if tag == "" || strings.ContainsAny(tag, " \t\n,\"") {
+			return fmt.Errorf("invalid build tag '%s'", tag)
+		}
+	}
+
+	if strings.ContainsAny(rootBuild.LDFlags, "\n$`") {
+		return errors.New("invalid linker flags, they can't contain newlines, '$' or '`'")
+	}
+
+	return nil
+}
+
+// checkRootBuildTargets returns an error if the webserver is built with cgo for a target other than
+// the platform of the build container, which the C toolchain installed in it can't link for
+func checkRootBuildTargets(rootBuild *RJRootBuild, targets []buildTarget) error {
+	if rootBuild == nil || !rootBuild.CGO {
+		return nil
+	}
+
+	if len(targets) == 0 {
+		targets = []buildTarget{{GoArch: runtime.GOARCH, GoOS: runtime.GOOS}}
 	}
 
-	go manageProcessReaping(cmd, killChannel)
+	for _, target := range targets {
+		if target.GoOS != "linux" || target.GoArch != runtime.GOARCH {
+			return fmt.Errorf("the webserver is built with cgo, which can only build it for linux/%s, not for %s", runtime.GOARCH, target)
+		}
+	}
 
-	err = cmd.Wait()
+	return nil
+}
 
-	// Indicates that 'manageProcessReaping' can exit
-	killChannel <- RJSignal{}
+// getRootVersion gets the version of the project root like 'git describe --tags --always' does:
+// the nearest tag, followed by the number of commits since it and the abbreviated commit if there
+// are any, or only the abbreviated commit without any tags; the full commit is returned as well
+func getRootVersion(rootPath string) (string, string, error) {
+	repository, err := git.PlainOpen(rootPath)
 
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	dockerBuildName := generateID()
+	head, err := repository.Head()
 
-	//Equivalent to "run --rm rj-root-build:latest"
-	runRootTransferArgs := []string{
-		"run", "--rm", "--name", dockerBuildName, "rj-root-build:latest",
+	if err != nil {
+		return "", "", err
 	}
 
-	cmd = exec.Command("docker", runRootTransferArgs...)
+	commit := head.Hash().String()
 
-	serverExecutable, err := os.Create(buildName)
+	tagReferences, err := repository.Tags()
 
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	cmd.Stdout = serverExecutable
+	tags := make(map[plumbing.Hash]string)
+
+	err = tagReferences.ForEach(func(reference *plumbing.Reference) error {
+		commitHash := reference.Hash()
+
+		// Annotated tags point to a tag object rather than to the commit itself
+		if tag, err := repository.TagObject(commitHash); err == nil {
+			tagCommit, err := tag.Commit()
+
+			if err != nil {
+				return nil
+			}
+
+			commitHash = tagCommit.Hash
+		}
+
+		tagName := reference.Name().Short()
+
+		// The version is passed to the linker in a space separated list of flags, which quotes are parsed in
+		if strings.ContainsAny(tagName, " \t\n'\"\\`$") {
+			return nil
+		}
+
+		// The greatest of several tags of a commit is used so that the version doesn't change between builds
+		if tagName > tags[commitHash] {
+			tags[commitHash] = tagName
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", "", err
+	}
 
-	err = cmd.Start()
+	commits, err := repository.Log(&git.LogOptions{From: head.Hash()})
 
 	if err != nil {
-		return err
+		return "", "", err
 	}
 
-	go manageRunReaping(dockerBuildName, killChannel)
+	version, distance := commit[:7], 0
+
+	err = commits.ForEach(func(logCommit *object.Commit) error {
+		if tagName, tagged := tags[logCommit.Hash]; tagged {
+			if version = tagName; distance != 0 {
+				version = fmt.Sprintf("%s-%d-g%s", tagName, distance, commit[:7])
+			}
+
+			return storer.ErrStop
+		}
 
-	err = cmd.Wait()
+		distance++
 
-	// Indicates that 'manageProcessReaping' can exit
-	killChannel <- RJSignal{}
+		return nil
+	})
 
-	return err
+	return version, commit, err
 }
 
-// Checks the old build hash against the current hash of the directly; the output hash is not always consistant,
-// so a hash is generated five times and compared against the old hash, returning true if any of the five match
-func checkProjectBuildHash(oldHash, projectPath string) bool {
-	for iteration := 0; iteration < 133; iteration++ {
-		if oldHash == dasher(projectPath, -1) {
-			return true
+// parseBuildTarget parses a target platform of the webserver in the form 'GOOS/GOARCH', with the
+// ARM version as a third part for 32-bit ARM, such as 'linux/amd64' or 'linux/arm/v7'
+func parseBuildTarget(target string) (buildTarget, error) {
+	parts := strings.Split(strings.TrimSpace(target), "/")
+
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return buildTarget{}, fmt.Errorf("invalid target '%s', expected 'GOOS/GOARCH' such as 'linux/amd64'", target)
+	}
+
+	parsedTarget := buildTarget{GoArch: parts[1], GoOS: parts[0]}
+
+	if len(parts) == 3 {
+		goARM := strings.TrimPrefix(parts[2], "v")
+
+		if parsedTarget.GoArch != "arm" || (goARM != "5" && goARM != "6" && goARM != "7") {
+			return buildTarget{}, fmt.Errorf("invalid target '%s', only 'arm' has a variant, which is one of 'v5', 'v6' or 'v7'", target)
 		}
+
+		parsedTarget.GoARM = goARM
 	}
-	return false
+
+	return parsedTarget, nil
+}
+
+// writeChecksums writes the SHA-256 checksums of the files to 'checksumPath' in the format of
+// 'sha256sum', so that they can be checked with 'sha256sum -c'
+func writeChecksums(checksumPath string, filePaths []string) error {
+	var checksums bytes.Buffer
+
+	for _, filePath := range filePaths {
+		file, err := os.Open(filePath)
+
+		if err != nil {
+			return err
+		}
+
+		hasher := sha256.New()
+
+		_, err = io.Copy(hasher, file)
+		file.Close()
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&checksums, "%x  %s\n", hasher.Sum(nil), filepath.Base(filePath))
+	}
+
+	return ioutil.WriteFile(checksumPath, checksums.Bytes(), 0644)
 }
 
 // checkProjectExistance checks if the project URL provided already exists (true)
@@ -256,71 +537,87 @@ func cloneProject(rjLocalProjectPath, rjProjectURL string) error {
 		return err
 	}
 
-	_, err := git.PlainClone(rjLocalProjectPath, false, &git.CloneOptions{
+	auth, err := getRemoteAuth(rjProjectURL)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = git.PlainClone(rjLocalProjectPath, false, &git.CloneOptions{
+		Auth:              auth,
 		URL:               rjProjectURL,
 		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
 	})
 	return err
 }
 
-func dasher(rootPath string, maxChanNumber int) string {
-	type directoryHasher struct {
-		directoryPaths []string
-		fileHashes     [][20]byte
-	}
+// copyDirectory copies the contents of the source directory into the destination directory, which must exist
+func copyDirectory(sourceDir, destinationDir string) error {
+	return copyDirectorySkipping(sourceDir, destinationDir, nil)
+}
 
-	if maxChanNumber < 1 {
-		maxChanNumber = runtime.GOMAXPROCS(0)
-	}
+// copyDirectorySkipping copies the contents of the source directory into the destination directory
+// like 'copyDirectory', except for files and directories with one of the names to skip
+func copyDirectorySkipping(sourceDir, destinationDir string, skipNames map[string]bool) error {
+	return filepath.Walk(sourceDir, func(sourcePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 
-	currentChanNumber := 0
-	pathChan := make(chan directoryHasher, maxChanNumber)
-	checkPaths := []string{rootPath}
-	hasher := sha1.New()
+		relativePath, err := filepath.Rel(sourceDir, sourcePath)
 
-	getFileHashes := func(dirname string) {
-		if f, err := os.Open(dirname); err != nil {
-			pathChan <- directoryHasher{}
-		} else {
-			foundPaths, err := f.Readdir(-1)
-			f.Close()
-			if err != nil {
-				pathChan <- directoryHasher{}
-			} else {
-				returnDirectorySearched := directoryHasher{make([]string, 0), make([][20]byte, 0)}
-				for _, foundPath := range foundPaths {
-					if foundPath.IsDir() {
-						returnDirectorySearched.directoryPaths = append(returnDirectorySearched.directoryPaths, path.Join(dirname, foundPath.Name()))
-					} else {
-						if fileBytes, err := ioutil.ReadFile(path.Join(dirname, foundPath.Name())); err == nil {
-							returnDirectorySearched.fileHashes = append(returnDirectorySearched.fileHashes, sha1.Sum(fileBytes))
-						}
-					}
-				}
-				pathChan <- returnDirectorySearched
+		if err != nil {
+			return err
+		}
+
+		if relativePath != "." && skipNames[fileInfo.Name()] {
+			if fileInfo.IsDir() {
+				return filepath.SkipDir
 			}
+
+			return nil
 		}
-	}
 
-	for len(checkPaths) != 0 || currentChanNumber != 0 {
-		if len(checkPaths) != 0 && currentChanNumber != maxChanNumber {
-			checkPath := checkPaths[0]
-			checkPaths = append(checkPaths[:0], checkPaths[1:]...)
+		destinationPath := filepath.Join(destinationDir, relativePath)
 
-			go getFileHashes(checkPath)
-			currentChanNumber++
-		} else {
-			pathSearch := <-pathChan
-			currentChanNumber--
+		switch {
+		case fileInfo.IsDir():
+			return os.MkdirAll(destinationPath, fileInfo.Mode().Perm()|0700)
+		case fileInfo.Mode()&os.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(sourcePath)
 
-			checkPaths = append(checkPaths, pathSearch.directoryPaths...)
-			for _, fileHash := range pathSearch.fileHashes {
-				hasher.Write(fileHash[:])
+			if err != nil {
+				return err
 			}
+
+			return os.Symlink(linkTarget, destinationPath)
 		}
+
+		return copyFile(sourcePath, destinationPath, fileInfo.Mode().Perm())
+	})
+}
+
+func copyFile(sourcePath, destinationPath string, mode os.FileMode) error {
+	sourceFile, err := os.Open(sourcePath)
+
+	if err != nil {
+		return err
+	}
+
+	defer sourceFile.Close()
+
+	destinationFile, err := os.OpenFile(destinationPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+
+	if err != nil {
+		return err
 	}
 
-	return fmt.Sprintf("%x", hasher.Sum(nil))
+	if _, err = io.Copy(destinationFile, sourceFile); err != nil {
+		destinationFile.Close()
+		return err
+	}
+
+	return destinationFile.Close()
 }
 
 func fileSearcher(findFile, rootPath string, maxChanNumber int) []string {
@@ -378,6 +675,24 @@ func fileSearcher(findFile, rootPath string, maxChanNumber int) []string {
 	return foundPaths
 }
 
+// formatSize formats a number of bytes for people to read
+func formatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	divisor, exponent := int64(unit), 0
+
+	for quotient := size / unit; quotient >= unit; quotient /= unit {
+		divisor *= unit
+		exponent++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(divisor), "KMGTPE"[exponent])
+}
+
 func generateID() string {
 	var buffer bytes.Buffer
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -390,6 +705,82 @@ func generateID() string {
 	return buffer.String()
 }
 
+// getBuildDigest hashes everything besides the project's files that its build output depends on: the
+// resolved build configuration, the rendered Dockerfile, the site path, asset stages and checks.
+// Remote projects are resolved without their files, which their commit covers instead, so neither
+// detection nor a Dockerfile template of the project's own is part of their digest
+func getBuildDigest(rjProject RJProject, projectPath, projectRoot, siteURL string) (string, error) {
+	remote := projectPath == ""
+	rjProject, buildConfig := resolveBuildConfig(rjProject, projectPath, siteURL, remote)
+
+	digestInput := buildDigestInput{
+		AssetStages:        rjProject.AssetStages,
+		BuildConfig:        buildConfig,
+		Checks:             rjProject.Checks,
+		DockerfileTemplate: rjProject.Dockerfile,
+		SitePath:           rjProject.SitePath,
+	}
+
+	if buildConfig.Framework != frameworkStatic && !(remote && rjProject.Dockerfile != "") {
+		dockerfile, err := renderReactDockerfile(rjProject, buildConfig, projectRoot, projectPath, remote)
+
+		if err != nil {
+			return "", err
+		}
+
+		digestInput.Dockerfile = dockerfile
+	}
+
+	// Maps are encoded with sorted keys, so the encoding only changes when the inputs do
+	encoded, err := json.Marshal(digestInput)
+
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(encoded)), nil
+}
+
+// getBuildReason gets the reason a project is built from the input hash (or remote commit) it would
+// be built from and the one it was last built from, which is unchanged if it shouldn't be built
+func getBuildReason(current, previous string, remote, force bool) string {
+	switch {
+	case previous == "" && remote:
+		return buildReasonNoPreviousCommit
+	case previous == "":
+		return buildReasonNoPreviousHash
+	case current != previous && remote:
+		return buildReasonNewCommit
+	case current != previous:
+		return buildReasonChangedHash
+	case force:
+		return buildReasonForced
+	}
+
+	return buildReasonUnchanged
+}
+
+// getRemoteBuildReason gets the reason a remote project is built, which is also rebuilt from the
+// commit it was last built from when its build digest changed since
+func getRemoteBuildReason(remoteCommit, buildDigest string, rjLocalProject RJLocalProject, force bool) string {
+	reason := getBuildReason(remoteCommit, rjLocalProject.LastBuildCommit, true, force)
+
+	if (reason == buildReasonUnchanged || reason == buildReasonForced) && buildDigest != rjLocalProject.LastBuildConfig {
+		return buildReasonChangedConfig
+	}
+
+	return reason
+}
+
+// getBuildRef gets the ref a project is built from remotely, the '--ref' flag overrides the project's
+func getBuildRef(rjProject RJProject, options buildOptions) string {
+	if options.ref != "" {
+		return options.ref
+	}
+
+	return rjProject.Ref
+}
+
 func getDirMap(rootDir, dirName string, fromRoot uint64) dirMap {
 	directory, err := os.Open(path.Join(rootDir, dirName))
 
@@ -419,6 +810,25 @@ func getDirMap(rootDir, dirName string, fromRoot uint64) dirMap {
 	return returnDirMap
 }
 
+// getDirectorySize adds up the size of every file in the directory
+func getDirectorySize(directoryPath string) (int64, error) {
+	var size int64
+
+	err := filepath.Walk(directoryPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if fileInfo.Mode().IsRegular() {
+			size += fileInfo.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
 func getGithubToken(path string) (string, error) {
 	file, err := os.Open(path)
 	defer file.Close()
@@ -495,24 +905,314 @@ func getProjectIndex(identifier string, projects []RJProject) int {
 	return -1
 }
 
-func getRemoteProjectCommit(projectURL string) (string, error) {
-	repository, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
-		URL: projectURL,
-	})
+// getProjectBaseURL gets the URL the project is served under, which is its site path under the
+// site URL; without a site URL the site is assumed to be served from the root of its domain, in
+// which case only the path is returned
+func getProjectBaseURL(siteURL, sitePath string) string {
+	basePath := path.Join("/", filepath.ToSlash(sitePath))
+
+	if basePath != "/" {
+		basePath += "/"
+	}
+
+	return strings.TrimSuffix(siteURL, "/") + basePath
+}
+
+// checkSiteURL returns an error if the site URL isn't an absolute HTTP(S) URL projects can be served under
+func checkSiteURL(siteURL string) error {
+	parsedURL, err := url.Parse(siteURL)
+
+	if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" || parsedURL.RawQuery != "" || parsedURL.Fragment != "" {
+		return fmt.Errorf("invalid site URL '%s', expected an absolute HTTP(S) URL without a query or fragment", siteURL)
+	}
+
+	return nil
+}
+
+// applyBaseURL sets up the build configuration to build the project for being served under
+// 'baseURL' without changing the project itself; Create React App reads it from PUBLIC_URL and
+// Vite is passed '--base', while SvelteKit and Next.js have no such variable or flag, so the path is
+// only passed as BASE_PATH and NEXT_PUBLIC_BASE_PATH for their configs to set 'kit.paths.base' and
+// 'basePath' from. Variables set in the project's build environment take precedence
+func applyBaseURL(buildConfig reactBuildConfig, baseURL string) reactBuildConfig {
+	basePath := baseURL
+
+	if parsedURL, err := url.Parse(baseURL); err == nil {
+		basePath = parsedURL.Path
+	}
+
+	buildEnv := map[string]string{
+		"BASE_PATH":             strings.TrimSuffix(basePath, "/"),
+		"NEXT_PUBLIC_BASE_PATH": strings.TrimSuffix(basePath, "/"),
+		"PUBLIC_URL":            strings.TrimSuffix(baseURL, "/"),
+	}
+
+	for key, value := range buildConfig.BuildEnv {
+		buildEnv[key] = value
+	}
+
+	buildConfig.BaseURL = baseURL
+	buildConfig.BuildEnv = buildEnv
+
+	if buildConfig.Framework == frameworkVite {
+		// npm passes arguments to the script only after '--', the other package managers pass them all
+		if buildConfig.PackageManager == packageManagerNpm {
+			buildConfig.ScriptArgs = append(buildConfig.ScriptArgs, "--")
+		}
+
+		buildConfig.ScriptArgs = append(buildConfig.ScriptArgs, "--base", baseURL)
+	}
+
+	return buildConfig
+}
+
+// getReactBuildConfig fills in the defaults for any build configuration the project does not specify,
+// the defaults of the project's framework and package manager are used when it has them
+func getReactBuildConfig(rjProject RJProject) reactBuildConfig {
+	buildConfig := reactBuildConfig{
+		BuildEnv:       rjProject.BuildEnv,
+		BuildScript:    rjProject.BuildScript,
+		Framework:      rjProject.Framework,
+		InputDirs:      make([]string, 0),
+		InstallCommand: rjProject.InstallCommand,
+		NodeVersion:    rjProject.NodeVersion,
+		OutputDir:      path.Clean(filepath.ToSlash(rjProject.OutputDir)),
+		PackageManager: rjProject.PackageManager,
+	}
+
+	frameworkConfig, hasFramework := frameworkDefaults[rjProject.Framework]
+
+	if buildConfig.BuildScript == "" {
+		buildConfig.BuildScript = defaultBuildScript
+	}
+
+	if _, exists := packageManagerInstallCommands[buildConfig.PackageManager]; !exists {
+		buildConfig.PackageManager = packageManagerNpm
+	}
+
+	if buildConfig.InstallCommand == "" {
+		buildConfig.InstallCommand = packageManagerInstallCommands[buildConfig.PackageManager]
+	}
+
+	// The default node version is kept for the Create React App projects ROB was made for, pnpm is
+	// enabled through corepack which node only has since 16.9
+	if buildConfig.NodeVersion == "" && frameworkConfig.nodeVersion != "" {
+		buildConfig.NodeVersion = frameworkConfig.nodeVersion
+	} else if buildConfig.NodeVersion == "" && buildConfig.PackageManager == packageManagerPnpm {
+		buildConfig.NodeVersion = currentNodeVersion
+	} else if buildConfig.NodeVersion == "" {
+		buildConfig.NodeVersion = defaultNodeVersion
+	}
+
+	if rjProject.OutputDir == "" && hasFramework {
+		buildConfig.OutputDir = frameworkConfig.outputDir
+	} else if rjProject.OutputDir == "" {
+		buildConfig.OutputDir = defaultOutputDir
+	}
+
+	inputDirs := rjProject.InputDirs
+
+	if len(inputDirs) == 0 && hasFramework {
+		inputDirs = frameworkConfig.inputDirs
+	} else if len(inputDirs) == 0 {
+		inputDirs = defaultInputDirs
+	}
+
+	for _, inputDir := range inputDirs {
+		buildConfig.InputDirs = append(buildConfig.InputDirs, path.Clean(filepath.ToSlash(inputDir)))
+	}
+
+	return buildConfig
+}
+
+// resolveBuildConfig resolves the project's build configuration as it's built for the site URL
+// provided, detecting what the project doesn't specify from the project at 'projectPath' if it's
+// given; the default input directories of local projects are narrowed down to those that exist
+func resolveBuildConfig(rjProject RJProject, projectPath, siteURL string, remote bool) (RJProject, reactBuildConfig) {
+	if projectPath != "" {
+		rjProject = applyDetectedProject(rjProject, projectPath)
+	}
+
+	buildConfig := getReactBuildConfig(rjProject)
+
+	if buildConfig.Framework == frameworkStatic {
+		return rjProject, buildConfig
+	}
+
+	buildConfig = applyBaseURL(buildConfig, getProjectBaseURL(siteURL, rjProject.SitePath))
+
+	// The default input directories of a framework are only those its projects usually have
+	if len(rjProject.InputDirs) == 0 && !remote {
+		inputDirs := make([]string, 0, len(buildConfig.InputDirs))
+
+		for _, inputDir := range buildConfig.InputDirs {
+			if _, err := os.Stat(filepath.Join(projectPath, filepath.FromSlash(inputDir))); err == nil {
+				inputDirs = append(inputDirs, inputDir)
+			}
+		}
+
+		buildConfig.InputDirs = inputDirs
+	}
+
+	return rjProject, buildConfig
+}
+
+// getReactBuildImage gives every project its own image tag so that projects can be built concurrently
+func getReactBuildImage(rjProject RJProject) string {
+	return fmt.Sprintf("rj-react-build:%s", strings.ToLower(rjProject.ID))
+}
+
+// getRemoteProjectCommit resolves 'ref' in the remote repository to the SHA of the commit it points
+// to; the ref can be a branch, a tag or a full commit SHA, and the remote's HEAD is used if it's empty
+func getRemoteProjectCommit(ctx context.Context, projectURL, ref string) (string, error) {
+	commitHash, err := resolveRemoteRef(ctx, projectURL, ref)
 
 	if err != nil {
 		return "", err
 	}
 
-	ref, err := repository.Head()
+	return commitHash.String(), nil
+}
+
+// cloneRemoteProjectToMemory clones the remote repository into memory, without a worktree
+func cloneRemoteProjectToMemory(ctx context.Context, projectURL string) (*git.Repository, error) {
+	auth, err := getRemoteAuth(projectURL)
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	remoteHash := ref.Hash()
+	repository, err := git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
+		Auth: auth,
+		Tags: git.AllTags,
+		URL:  projectURL,
+	})
 
-	return remoteHash.String(), nil
+	if err != nil {
+		if ctxErr := getContextError(ctx, "git fetch"); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		return nil, err
+	}
+
+	return repository, nil
+}
+
+// listRemoteRefs lists the references of the remote repository like 'git ls-remote' does, without
+// fetching any objects; annotated tags are listed along with the commits they point to
+func listRemoteRefs(ctx context.Context, projectURL string) (*packp.AdvRefs, error) {
+	auth, err := getRemoteAuth(projectURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := transport.NewEndpoint(projectURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	gitClient, err := client.NewClient(endpoint)
+
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := gitClient.NewUploadPackSession(endpoint, auth)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer session.Close()
+
+	advertisedRefs, err := session.AdvertisedReferences()
+
+	if err != nil {
+		if ctxErr := getContextError(ctx, "git ls-remote"); ctxErr != nil {
+			return nil, ctxErr
+		}
+
+		return nil, err
+	}
+
+	return advertisedRefs, nil
+}
+
+// resolveRemoteRef resolves 'ref' in the remote repository to the hash of the commit it points to
+// from the remote's references, see 'getRemoteProjectCommit'; only a commit SHA which no reference
+// points to needs the repository to be fetched to check that the commit exists
+func resolveRemoteRef(ctx context.Context, projectURL, ref string) (plumbing.Hash, error) {
+	advertisedRefs, err := listRemoteRefs(ctx, projectURL)
+
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if ref == "" {
+		if advertisedRefs.Head == nil {
+			return plumbing.ZeroHash, errors.New("the remote repository does not have a HEAD")
+		}
+
+		return *advertisedRefs.Head, nil
+	}
+
+	for _, referenceName := range []string{"refs/heads/" + ref, "refs/tags/" + ref, ref} {
+		// Annotated tags point to a tag object rather than to the commit itself
+		if commitHash, exists := advertisedRefs.Peeled[referenceName]; exists {
+			return commitHash, nil
+		}
+
+		if commitHash, exists := advertisedRefs.References[referenceName]; exists {
+			return commitHash, nil
+		}
+	}
+
+	if len(ref) != 40 || strings.Trim(strings.ToLower(ref), "0123456789abcdef") != "" {
+		return plumbing.ZeroHash, fmt.Errorf("ref '%s' is not a branch, tag or full commit SHA in the remote repository", ref)
+	}
+
+	commitHash := plumbing.NewHash(ref)
+
+	for _, referencedHash := range advertisedRefs.References {
+		if referencedHash == commitHash {
+			return commitHash, nil
+		}
+	}
+
+	for _, referencedHash := range advertisedRefs.Peeled {
+		if referencedHash == commitHash {
+			return commitHash, nil
+		}
+	}
+
+	repository, err := cloneRemoteProjectToMemory(ctx, projectURL)
+
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if _, err = repository.CommitObject(commitHash); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("commit '%s' is not in the remote repository", ref)
+	}
+
+	return commitHash, nil
+}
+
+// getRootBuildReason gets the reason the webserver is built, which is unchanged if it shouldn't be
+func getRootBuildReason(localCommit, lastBuildCommit string, force bool) string {
+	switch {
+	case localCommit != lastBuildCommit && lastBuildCommit == "":
+		return buildReasonNoPreviousCommit
+	case localCommit != lastBuildCommit:
+		return buildReasonNewLocalCommit
+	case force:
+		return buildReasonForced
+	}
+
+	return buildReasonUnchanged
 }
 
 func getRjGlobal(projectRootPath string) (RJGlobal, error) {
@@ -522,15 +1222,19 @@ func getRjGlobal(projectRootPath string) (RJGlobal, error) {
 		return RJGlobal{}, newErrRjFileNotFound(true, false)
 	}
 
-	rjGlobalFile, err := os.Open(path.Join(projectRootPath, "RJglobal.json"))
+	rjGlobalBytes, err := ioutil.ReadFile(path.Join(projectRootPath, "RJglobal.json"))
+
+	if err != nil {
+		return RJGlobal{}, err
+	}
 
-	defer rjGlobalFile.Close()
+	rjGlobalBytes, _, err = migrateRjFile("RJglobal.json", rjGlobalBytes, rjGlobalMigrations)
 
 	if err != nil {
 		return RJGlobal{}, err
 	}
 
-	if err := json.NewDecoder(rjGlobalFile).Decode(&rjGlobal); err != nil {
+	if err := json.Unmarshal(rjGlobalBytes, &rjGlobal); err != nil {
 		return RJGlobal{}, err
 	}
 
@@ -538,32 +1242,40 @@ func getRjGlobal(projectRootPath string) (RJGlobal, error) {
 }
 
 func getRjInfo(projectRootPath string) (*RJInfo, error) {
-	var err error
-	var rjInfo RJInfo
+	return loadRjInfo(projectRootPath, false)
+}
 
-	if rjInfo.RJGlobal, err = getRjGlobal(projectRootPath); err != nil {
-		return &rjInfo, err
+// getRjInfoLocked locks the project root before loading RJInfo so that the caller can update it
+// without racing other ROB instances; the lock must be released once the update has been written
+func getRjInfoLocked(projectRootPath string) (*RJInfo, *rjLock, error) {
+	lock, err := lockProjectRoot(projectRootPath, lockTimeout)
+
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if rjInfo.RJLocal, err = getRjLocal(projectRootPath); err != nil {
-		if _, ok := err.(*errRjFileNotFound); ok {
-			rjInfo.RJLocal, err = initializeLocal(projectRootPath, rjInfo.RJGlobal, false)
-		} else {
-			return &rjInfo, err
-		}
+	rjInfo, err := loadRjInfo(projectRootPath, true)
+
+	if err != nil {
+		lock.Unlock()
+		return nil, nil, err
 	}
 
-	return &rjInfo, err
+	return rjInfo, lock, nil
 }
 
 func getRjLocal(projectRootPath string) (RJLocal, error) {
-	if _, err := os.Stat(path.Join(projectRootPath, "RJglobal.json")); err != nil {
+	if _, err := os.Stat(path.Join(projectRootPath, "RJlocal.json")); err != nil {
 		return RJLocal{}, newErrRjFileNotFound(false, true)
 	}
 
-	rjLocalFile, err := os.Open(path.Join(projectRootPath, "RJlocal.json"))
+	rjLocalBytes, err := ioutil.ReadFile(path.Join(projectRootPath, "RJlocal.json"))
 
-	defer rjLocalFile.Close()
+	if err != nil {
+		return RJLocal{}, err
+	}
+
+	rjLocalBytes, _, err = migrateRjFile("RJlocal.json", rjLocalBytes, rjLocalMigrations)
 
 	if err != nil {
 		return RJLocal{}, err
@@ -571,7 +1283,7 @@ func getRjLocal(projectRootPath string) (RJLocal, error) {
 
 	rjLocal := RJLocal{Projects: make(map[string]RJLocalProject)}
 
-	if err := json.NewDecoder(rjLocalFile).Decode(&rjLocal); err != nil {
+	if err := json.Unmarshal(rjLocalBytes, &rjLocal); err != nil {
 		return RJLocal{}, err
 	}
 
@@ -616,24 +1328,140 @@ func handleCloneProject(rjProject *RJProject, rjLocal *RJLocal, force bool) erro
 	return nil
 }
 
-func handleSyncronizeLocal(rjProject *RJProject, rjLocal *RJLocal) (string, error) {
+func handleSyncronizeLocal(ctx context.Context, rjProject *RJProject, rjLocal *RJLocal) (string, error) {
 	rjLocalProject, rjLocalProjectExists := rjLocal.Projects[rjProject.ID]
 
 	if !rjLocalProjectExists || rjLocalProject.Path == "" {
 		return "", fmt.Errorf("project '%s' does not exist locally", rjProject.Name)
 	}
 
-	newlySynced, err := syncronizeLocal(*rjProject, rjLocalProject)
+	newlySynced, err := syncronizeLocal(ctx, *rjProject, rjLocalProject)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "problem syncing Project '%s'", rjProject.Name)
+	}
+
+	if newlySynced {
+		return fmt.Sprintf("Project '%s' has been synced.", rjProject.Name), nil
+	}
+
+	return fmt.Sprintf("Project '%s' is already in sync.", rjProject.Name), nil
+}
+
+// hashProject hashes the inputs of the project at 'projectPath', returning the hash and the
+// slash-separated paths of the files hashed; files are visited in lexical order and the relative
+// path, mode and contents of each are hashed so that the result only changes when the inputs do.
+// '.git', 'node_modules', the build output and paths ignored by '.gitignore' files are skipped,
+// and when the project has hash inputs only files matching one of those globs are included. The
+// project's build digest is hashed first, so changes to how it's built change the hash as well
+func hashProject(projectPath, projectRoot, siteURL string, rjProject RJProject) (string, []string, error) {
+	buildDigest, err := getBuildDigest(rjProject, projectPath, projectRoot, siteURL)
+
+	if err != nil {
+		return "", nil, errors.Wrap(err, "problem getting the build digest")
+	}
+
+	_, buildConfig := resolveBuildConfig(rjProject, projectPath, siteURL, false)
+	hashedFiles := make([]string, 0)
+	hasher := sha256.New()
+	ignorePatterns := make([]gitignore.Pattern, 0)
+
+	fmt.Fprintf(hasher, "%s\x00", buildDigest)
+
+	err = filepath.Walk(projectPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(projectPath, filePath)
+
+		if err != nil {
+			return err
+		}
+
+		relativePath = filepath.ToSlash(relativePath)
+
+		var pathParts []string
+
+		if relativePath != "." {
+			pathParts = strings.Split(relativePath, "/")
+		}
+
+		if fileInfo.IsDir() {
+			if relativePath != "." {
+				if fileInfo.Name() == ".git" || fileInfo.Name() == "node_modules" || relativePath == buildConfig.OutputDir {
+					return filepath.SkipDir
+				}
+
+				if gitignore.NewMatcher(ignorePatterns).Match(pathParts, true) {
+					return filepath.SkipDir
+				}
+			}
+
+			patterns, err := readGitignore(filePath, pathParts)
+
+			if err != nil {
+				return err
+			}
+
+			// Walking is depth first, so patterns from a directory are read before any of its contents
+			ignorePatterns = append(ignorePatterns, patterns...)
+			return nil
+		}
+
+		if fileInfo.Name() == ".RJtag" || gitignore.NewMatcher(ignorePatterns).Match(pathParts, false) {
+			return nil
+		}
+
+		if len(rjProject.HashInputs) != 0 && !matchHashInputs(relativePath, rjProject.HashInputs) {
+			return nil
+		}
+
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			linkTarget, err := os.Readlink(filePath)
+
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(hasher, "%s\x00%o\x00%d\x00%s", relativePath, fileInfo.Mode(), len(linkTarget), linkTarget)
+			hashedFiles = append(hashedFiles, relativePath)
+			return nil
+		}
+
+		if !fileInfo.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(filePath)
+
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		fmt.Fprintf(hasher, "%s\x00%o\x00%d\x00", relativePath, fileInfo.Mode().Perm(), fileInfo.Size())
+
+		written, err := io.Copy(hasher, file)
+
+		if err != nil {
+			return err
+		}
+
+		if written != fileInfo.Size() {
+			return errors.Errorf("'%s' changed while it was being hashed", relativePath)
+		}
 
-	if err != nil {
-		return "", errors.Wrapf(err, "problem syncing Project '%s'", rjProject.Name)
-	}
+		hashedFiles = append(hashedFiles, relativePath)
+		return nil
+	})
 
-	if newlySynced {
-		return fmt.Sprintf("Project '%s' has been synced.", rjProject.Name), nil
+	if err != nil {
+		return "", nil, err
 	}
 
-	return fmt.Sprintf("Project '%s' is already in sync.", rjProject.Name), nil
+	return fmt.Sprintf("%x", hasher.Sum(nil)), hashedFiles, nil
 }
 
 func initializeGlobal(projectRootPath string, force bool) (RJGlobal, error) {
@@ -645,17 +1473,9 @@ func initializeGlobal(projectRootPath string, force bool) (RJGlobal, error) {
 		return RJGlobal{}, errors.New("path to RJglobal file already exists, add the -force flag to overwrite the current config file")
 	}
 
-	rjGlobalFile, err := os.Create(path.Join(projectRootPath, "RJglobal.json"))
-
-	defer rjGlobalFile.Close()
-
-	if err != nil {
-		return RJGlobal{}, err
-	}
-
-	rjGlobal := RJGlobal{Projects: make([]RJProject, 0)}
+	rjGlobal := RJGlobal{Projects: make([]RJProject, 0), SchemaVersion: rjGlobalSchemaVersion}
 
-	if err = json.NewEncoder(rjGlobalFile).Encode(&rjGlobal); err != nil {
+	if err := commitRjFiles(projectRootPath, rjFile{"RJglobal.json", &rjGlobal}); err != nil {
 		return RJGlobal{}, errors.Wrap(err, "error initializing RJglobal file")
 	}
 
@@ -671,22 +1491,14 @@ func initializeLocal(projectRoot string, rjGlobal RJGlobal, force bool) (RJLocal
 		return RJLocal{}, errors.New("path to RJlocal file already exists, add the -force flag to overwrite the current local file")
 	}
 
-	rjLocalFile, err := os.Create(path.Join(projectRoot, "RJlocal.json"))
-
-	defer rjLocalFile.Close()
-
-	if err != nil {
-		return RJLocal{}, err
-	}
-
-	rjLocal := RJLocal{Projects: make(map[string]RJLocalProject), SearchPaths: make([]string, 0)}
+	rjLocal := RJLocal{Projects: make(map[string]RJLocalProject), SchemaVersion: rjLocalSchemaVersion, SearchPaths: make([]string, 0)}
 
 	for _, rjProject := range rjGlobal.Projects {
 		rjLocal.Projects[rjProject.ID] = RJLocalProject{}
 	}
 
-	if err = json.NewEncoder(rjLocalFile).Encode(&rjLocal); err != nil {
-		return RJLocal{}, errors.Wrap(err, "error initializing RJglobal file")
+	if err := commitRjFiles(projectRoot, rjFile{"RJlocal.json", &rjLocal}); err != nil {
+		return RJLocal{}, errors.Wrap(err, "error initializing RJlocal file")
 	}
 
 	return rjLocal, nil
@@ -722,7 +1534,43 @@ func killServer() error {
 	return err
 }
 
-func localProjectSynced(localProjectPath, projectURL, projectName string) (bool, error) {
+// loadRjInfo loads RJglobal and RJlocal, initializing RJlocal if it does not exist yet; the
+// initialization takes the project root lock itself unless the caller indicates it is already held
+func loadRjInfo(projectRootPath string, locked bool) (*RJInfo, error) {
+	var err error
+	rjInfo := RJInfo{localLock: new(sync.Mutex)}
+
+	if rjInfo.RJGlobal, err = getRjGlobal(projectRootPath); err != nil {
+		return &rjInfo, err
+	}
+
+	if rjInfo.RJLocal, err = getRjLocal(projectRootPath); err != nil {
+		if _, ok := err.(*errRjFileNotFound); !ok {
+			return &rjInfo, err
+		}
+
+		if !locked {
+			lock, err := lockProjectRoot(projectRootPath, lockTimeout)
+
+			if err != nil {
+				return &rjInfo, err
+			}
+
+			defer lock.Unlock()
+
+			// Another ROB instance may have initialized RJlocal while waiting for the lock
+			if rjInfo.RJLocal, err = getRjLocal(projectRootPath); err == nil {
+				return &rjInfo, nil
+			}
+		}
+
+		rjInfo.RJLocal, err = initializeLocal(projectRootPath, rjInfo.RJGlobal, false)
+	}
+
+	return &rjInfo, err
+}
+
+func localProjectSynced(ctx context.Context, localProjectPath, projectURL, projectName string) (bool, error) {
 	fileInfo, err := os.Lstat(localProjectPath)
 
 	if err != nil {
@@ -740,7 +1588,7 @@ func localProjectSynced(localProjectPath, projectURL, projectName string) (bool,
 
 	}
 
-	remoteProjectHash, err := getRemoteProjectCommit(projectURL)
+	remoteProjectHash, err := getRemoteProjectCommit(ctx, projectURL, "")
 
 	if err != nil {
 		return false, errors.Wrapf(err, "Could not get remote commit hash of project '%s'.\n", projectName)
@@ -749,41 +1597,20 @@ func localProjectSynced(localProjectPath, projectURL, projectName string) (bool,
 	return localProjectHash == remoteProjectHash, nil
 }
 
-// Given that the 'killChannel' channel has already been created and registered with signal.Notify,
-// this will handle killing the created process in the case this program is ended via CTRL+C
-func manageProcessReaping(command *exec.Cmd, killChannel chan os.Signal) {
-	signal := <-killChannel
-	if signal.String() == "RJ" {
-		return
-	}
+// matchHashInputs checks if the slash-separated path, or any directory containing it, matches
+// one of the hash input globs
+func matchHashInputs(relativePath string, hashInputs []string) bool {
+	for _, hashInput := range hashInputs {
+		hashInput = path.Clean(strings.TrimPrefix(filepath.ToSlash(hashInput), "./"))
 
-	if command.Process != nil {
-		if runtime.GOOS == "windows" {
-			subcommand := exec.Command("TASKKILL", "/T", "/F", "/PID", strconv.Itoa(command.Process.Pid))
-			subcommand.Stdout = os.Stdout
-			subcommand.Stderr = os.Stderr
-			err := subcommand.Run()
-			if err != nil {
-				fmt.Println(err)
+		for checkPath := relativePath; checkPath != "."; checkPath = path.Dir(checkPath) {
+			if matched, _ := path.Match(hashInput, checkPath); matched {
+				return true
 			}
-		} else {
-			command.Process.Kill()
 		}
 	}
-}
-
-// Given that the 'killChannel' channel has already been created and registered with signal.Notify,
-// this will handle killing a docker run command with a name matching the provided name param in
-// the case this program is ended via CTRL+C
-func manageRunReaping(name string, killChannel chan os.Signal) {
-	signal := <-killChannel
-	if signal.String() == "RJ" {
-		return
-	}
 
-	if err := exec.Command("docker", "stop", name).Run(); err != nil {
-		fmt.Println(err)
-	}
+	return false
 }
 
 func newDirMap(rootDir string) dirMap {
@@ -801,6 +1628,26 @@ func prettyPrintStruct(structure interface{}, spaces uint64) error {
 	return nil
 }
 
+// printBuildSummary prints a table of the results of building multiple projects
+func printBuildSummary(writer io.Writer, results []projectBuildResult) {
+	tabWriter := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tabWriter, "PROJECT\tRESULT\tDETAILS")
+
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", result.Name, getFailedBuildResult(result.Err), strings.Replace(result.Err.Error(), "\n", " ", -1))
+		case result.Built:
+			fmt.Fprintf(tabWriter, "%s\tbuilt\t\n", result.Name)
+		default:
+			fmt.Fprintf(tabWriter, "%s\tskipped\t\n", result.Name)
+		}
+	}
+
+	tabWriter.Flush()
+}
+
 func printProject(project RJProject, localProjects RJLocal, spaces uint64) error {
 	fmt.Printf("\n%s\n%s\n", project.Name, strings.Repeat("=", len(project.Name)))
 	err := prettyPrintStruct(project, spaces)
@@ -844,6 +1691,34 @@ func pruneLocal(rjInfo *RJInfo) int {
 	return pruned
 }
 
+// readGitignore reads the patterns of the '.gitignore' file in 'directoryPath', if there is one;
+// 'domain' is the path of the directory relative to the project as used by the patterns
+func readGitignore(directoryPath string, domain []string) ([]gitignore.Pattern, error) {
+	gitignoreBytes, err := ioutil.ReadFile(filepath.Join(directoryPath, ".gitignore"))
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]gitignore.Pattern, 0)
+
+	for _, line := range strings.Split(string(gitignoreBytes), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
+	}
+
+	return patterns, nil
+}
+
 func removeContents(directoryPath string) error {
 	directory, err := os.Open(directoryPath)
 
@@ -902,170 +1777,256 @@ func removeProjectLocally(project string, rjInfo *RJInfo) error {
 	return nil
 }
 
-func rjBuild(rjInfo *RJInfo, rjProject RJProject, projectRoot string, force bool) (bool, error) {
-	rjLocalProject, rjLocalProjectExists := rjInfo.RJLocal.Projects[rjProject.ID]
+// renderDockerfile renders the Dockerfile template with the data provided, 'quote' is available to
+// the template for quoting strings in JSON form, 'envQuote' for quoting them in ENV and ARG
+// instructions and 'shellQuote' for quoting them in shell commands
+func renderDockerfile(name, dockerfileTemplate string, data interface{}) (string, error) {
+	parsedTemplate, err := template.New(name).Funcs(template.FuncMap{"envQuote": envQuote, "quote": strconv.Quote, "shellQuote": shellQuote}).Parse(dockerfileTemplate)
 
-	if rjLocalProjectExists && rjLocalProject.Path != "" {
-		if rjLocalProject.LastBuildHash != "" {
-			dontBuild := checkProjectBuildHash(rjLocalProject.LastBuildHash, rjLocalProject.Path)
+	if err != nil {
+		return "", errors.Wrapf(err, "problem parsing the Dockerfile template '%s'", name)
+	}
 
-			if dontBuild {
-				if !force {
-					fmt.Printf("Build hash for Project '%s' is the same as the previous build hash, building skipped; to force building, specify the '-force' flag.\n", rjProject.Name)
-					return false, nil
-				}
-				fmt.Printf("Build hash for Project '%s' is the same as the previous build hash, build is being forced.", rjProject.Name)
-			} else {
-				fmt.Printf("Build hash for Project '%s' is different from the previous build hash, rebuilding.", rjProject.Name)
-			}
+	dockerfile := new(bytes.Buffer)
 
-			newBuildHash, err := buildProjectLocally(rjLocalProject.Path, projectRoot, rjProject.SitePath)
+	if err = parsedTemplate.Execute(dockerfile, data); err != nil {
+		return "", errors.Wrapf(err, "problem rendering the Dockerfile template '%s'", name)
+	}
 
-			if err != nil {
-				return false, errors.Wrapf(err, "problem building Project '%s'", rjProject.Name)
-			}
+	return dockerfile.String(), nil
+}
 
-			fmt.Printf("Project '%s' successfully built to sitepath '%s'.\n", rjProject.Name, rjProject.SitePath)
-			rjLocalProject.LastBuildHash = newBuildHash
-			rjInfo.RJLocal.Projects[rjProject.ID] = rjLocalProject
-			return true, nil
-		}
+// envQuote double quotes the string for an ENV or ARG instruction of a Dockerfile, which only
+// treats a backslash as an escape before '\', '"' and '$', so that nothing in it is expanded
+func envQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`).Replace(value) + `"`
+}
+
+// shellQuote quotes the string for a POSIX shell, nothing in it is expanded
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
 
-		fmt.Printf("Project '%s' does not have previous build hash, building now.\n", rjProject.Name)
+// renderReactDockerfile renders the project's Dockerfile template if it has one, which is relative
+// to the project at 'projectPath'; otherwise the local or remote React Dockerfile template in the
+// project root is rendered, or the default one if there is none
+func renderReactDockerfile(rjProject RJProject, buildConfig reactBuildConfig, projectRoot, projectPath string, remote bool) (string, error) {
+	templateName, dockerfileTemplate := reactLocalDockerfile, localReactBuild
 
-		newBuildHash, err := buildProjectLocally(rjLocalProject.Path, projectRoot, rjProject.SitePath)
+	if remote {
+		templateName, dockerfileTemplate = reactRemoteDockerfile, remoteReactBuild
+	}
+
+	if rjProject.Dockerfile != "" {
+		templateBytes, err := ioutil.ReadFile(filepath.Join(projectPath, filepath.FromSlash(rjProject.Dockerfile)))
 
 		if err != nil {
-			return false, errors.Wrapf(err, "problem building Project '%s'", rjProject.Name)
+			return "", errors.Wrapf(err, "problem reading the Dockerfile template of Project '%s'", rjProject.Name)
 		}
 
-		fmt.Printf("Project '%s' successfully built to sitepath '%s'.\n", rjProject.Name, rjProject.SitePath)
-		rjLocalProject.LastBuildHash = newBuildHash
-		rjInfo.RJLocal.Projects[rjProject.ID] = rjLocalProject
-		return true, nil
+		templateName, dockerfileTemplate = rjProject.Dockerfile, string(templateBytes)
+	} else if templateBytes, err := ioutil.ReadFile(filepath.Join(projectRoot, templateName)); err == nil {
+		dockerfileTemplate = string(templateBytes)
+	} else if !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, "problem reading the Dockerfile template '%s'", templateName)
 	}
 
-	fmt.Printf("Project '%s' does not exist locally, building in container.\n", rjProject.Name)
+	return renderDockerfile(templateName, dockerfileTemplate, reactDockerfileData{buildConfig, rjProject, remote})
+}
 
-	remoteCommit, err := getRemoteProjectCommit(rjProject.URL)
+// renderRootDockerfile renders the webserver's Dockerfile template in the project root, or the
+// default one if there is none
+func renderRootDockerfile(rootPath string, data rootDockerfileData) (string, error) {
+	dockerfileTemplate := rootBuild
 
-	if err != nil {
-		return false, errors.Wrapf(err, "problem getting the remote hash for Project '%s'", rjProject.Name)
+	if templateBytes, err := ioutil.ReadFile(filepath.Join(rootPath, rootDockerfile)); err == nil {
+		dockerfileTemplate = string(templateBytes)
+	} else if !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, "problem reading the Dockerfile template '%s'", rootDockerfile)
 	}
 
-	if rjLocalProjectExists && rjLocalProject.LastBuildCommit != "" {
-		if remoteCommit != rjLocalProject.LastBuildCommit {
-			err = buildProjectRemotely(projectRoot, rjProject.SitePath, rjProject.URL)
+	return renderDockerfile(rootDockerfile, dockerfileTemplate, data)
+}
 
-			if err != nil {
-				return false, errors.Wrapf(err, "problem building Project '%s' remotely", rjProject.Name)
-			}
+// rjBuild builds the project if its inputs changed since the last build, or if the build is forced,
+// and fills in what it decided in the build record provided
+func rjBuild(rjInfo *RJInfo, rjProject RJProject, projectRoot string, options buildOptions, record *buildRecord, output io.Writer) (bool, error) {
+	rjLocalProject, rjLocalProjectExists := rjInfo.getLocalProject(rjProject.ID)
 
-			fmt.Printf("Project '%s' has been successfully cloned to %s.\n", rjProject.Name, rjProject.SitePath)
-			rjLocalProject.LastBuildCommit = remoteCommit
-			rjInfo.RJLocal.Projects[rjProject.ID] = rjLocalProject
-			return true, nil
-		}
+	record.Native = options.native
 
-		if force {
-			fmt.Printf("Remote hash for Project '%s' is the same as the previous build's remote commit hash, build is being forced.", rjProject.Name)
+	if rjLocalProjectExists && rjLocalProject.Path != "" {
+		record.Mode = buildModeLocal
 
-			err = buildProjectRemotely(projectRoot, rjProject.SitePath, rjProject.URL)
+		currentHash, _, err := hashProject(rjLocalProject.Path, projectRoot, options.siteURL, rjProject)
 
-			if err != nil {
-				return false, errors.Wrapf(err, "problem building Project '%s' remotely", rjProject.Name)
-			}
+		if err != nil {
+			return false, errors.Wrapf(err, "problem hashing Project '%s'", rjProject.Name)
+		}
 
-			fmt.Printf("Project '%s' has been successfully cloned to %s.\n", rjProject.Name, rjProject.SitePath)
-			rjLocalProject.LastBuildCommit = remoteCommit
-			rjInfo.RJLocal.Projects[rjProject.ID] = rjLocalProject
-			return true, nil
+		record.Hash = currentHash
+		record.Previous = rjLocalProject.LastBuildHash
+		record.Trigger = getBuildReason(currentHash, rjLocalProject.LastBuildHash, false, options.force)
+
+		switch record.Trigger {
+		case buildReasonNoPreviousHash:
+			fmt.Fprintf(output, "Project '%s' does not have previous build hash, building now.\n", rjProject.Name)
+		case buildReasonUnchanged:
+			fmt.Fprintf(output, "Build hash for Project '%s' is the same as the previous build hash, building skipped; to force building, specify the '-force' flag.\n", rjProject.Name)
+			return false, nil
+		case buildReasonForced:
+			fmt.Fprintf(output, "Build hash for Project '%s' is the same as the previous build hash, build is being forced.\n", rjProject.Name)
+		default:
+			fmt.Fprintf(output, "Build hash for Project '%s' is different from the previous build hash, rebuilding.\n", rjProject.Name)
 		}
-		fmt.Printf("Remote hash for Project '%s' is the same as the previous build's remote commit hash, building skipped; to force building, specify the '-force' flag.\n", rjProject.Name)
-		return false, nil
 
+		if record.Restored, err = stageProjectRelease(rjProject, &rjLocalProject, rjLocalProject.Path, projectRoot, RJRelease{BuildHash: currentHash}, options, record, output); err != nil {
+			return false, errors.Wrapf(err, "problem building Project '%s'", rjProject.Name)
+		}
+
+		fmt.Fprintf(output, "Project '%s' successfully built to sitepath '%s' as release %d.\n", rjProject.Name, rjProject.SitePath, rjLocalProject.Release)
+		rjLocalProject.LastBuildHash = currentHash
+		rjInfo.setLocalProject(rjProject.ID, rjLocalProject)
+		return true, nil
+	}
+
+	record.Mode = buildModeRemote
+
+	if options.native {
+		fmt.Fprintf(output, "Project '%s' does not exist locally, building from a temporary clone.\n", rjProject.Name)
+	} else {
+		fmt.Fprintf(output, "Project '%s' does not exist locally, building in container.\n", rjProject.Name)
 	}
 
-	err = buildProjectRemotely(projectRoot, rjProject.SitePath, rjProject.URL)
+	remoteCommit, err := getRemoteProjectCommit(options.ctx, rjProject.URL, getBuildRef(rjProject, options))
 
 	if err != nil {
-		return false, errors.Wrapf(err, "problem building Project '%s' remotely", rjProject.Name)
+		return false, errors.Wrapf(err, "problem getting the remote hash for Project '%s'", rjProject.Name)
 	}
 
-	fmt.Printf("Project '%s' has been successfully cloned to %s.\n", rjProject.Name, rjProject.SitePath)
-	rjInfo.RJLocal.Projects[rjProject.ID] = RJLocalProject{LastBuildCommit: remoteCommit}
-	return true, nil
-}
+	buildDigest, err := getBuildDigest(rjProject, "", projectRoot, options.siteURL)
 
-func rjPushRob(tag string, local bool) error {
-	// Equivalent to "build --no-cache -t {tag} -f - /path/to/rob"
-	runRobInstallerArgs := []string{
-		"build", "--no-cache", "-t", fmt.Sprintf("therileyjohnson/rob:%s", tag),
-		"-f", "-", ".",
+	if err != nil {
+		return false, errors.Wrapf(err, "problem getting the build digest of Project '%s'", rjProject.Name)
 	}
 
-	cmd := exec.Command("docker", runRobInstallerArgs...)
+	record.Commit = remoteCommit
+	record.Previous = rjLocalProject.LastBuildCommit
+	record.Trigger = getRemoteBuildReason(remoteCommit, buildDigest, rjLocalProject, options.force)
 
-	if local {
-		cmd.Stdin = bytes.NewBufferString(robInstallBuilderLocal)
-	} else {
-		cmd.Stdin = bytes.NewBufferString(robInstallBuilderRemote)
+	switch record.Trigger {
+	case buildReasonUnchanged:
+		fmt.Fprintf(output, "Remote hash for Project '%s' is the same as the previous build's remote commit hash, building skipped; to force building, specify the '-force' flag.\n", rjProject.Name)
+		return false, nil
+	case buildReasonForced:
+		fmt.Fprintf(output, "Remote hash for Project '%s' is the same as the previous build's remote commit hash, build is being forced.\n", rjProject.Name)
+	case buildReasonChangedConfig:
+		fmt.Fprintf(output, "Build config for Project '%s' changed since the previous build, rebuilding.\n", rjProject.Name)
 	}
 
-	cmd.Stdout = os.Stdout
+	if record.Restored, err = stageProjectRelease(rjProject, &rjLocalProject, "", projectRoot, RJRelease{BuildCommit: remoteCommit, BuildConfig: buildDigest}, options, record, output); err != nil {
+		return false, errors.Wrapf(err, "problem building Project '%s' remotely", rjProject.Name)
+	}
 
-	killChannel := make(chan os.Signal, 1)
+	fmt.Fprintf(output, "Project '%s' has been successfully cloned to %s as release %d.\n", rjProject.Name, rjProject.SitePath, rjLocalProject.Release)
 
-	signal.Notify(killChannel,
-		syscall.SIGHUP,
-		syscall.SIGINT,
-		syscall.SIGTERM,
-		syscall.SIGQUIT,
-	)
+	rjLocalProject.LastBuildCommit = remoteCommit
+	rjLocalProject.LastBuildConfig = buildDigest
+	rjInfo.setLocalProject(rjProject.ID, rjLocalProject)
+	return true, nil
+}
 
-	err := cmd.Start()
+// rjBuildAll builds every project with a pool of 'options.jobs' workers, prefixing the output of each
+// project with its name; the results are in the same order as the projects in RJglobal
+func rjBuildAll(rjInfo *RJInfo, projectRoot string, options buildOptions) []projectBuildResult {
+	var outputLock sync.Mutex
+	var waitGroup sync.WaitGroup
 
-	if err != nil {
-		return err
-	}
+	projectIndexes := make(chan int)
+	results := make([]projectBuildResult, len(rjInfo.RJGlobal.Projects))
 
-	go manageProcessReaping(cmd, killChannel)
+	for worker := 0; worker < options.jobs; worker++ {
+		waitGroup.Add(1)
 
-	err = cmd.Wait()
+		go func() {
+			defer waitGroup.Done()
 
-	// Indicates that 'manageProcessReaping' can exit
-	killChannel <- RJSignal{}
+			for index := range projectIndexes {
+				rjProject := rjInfo.RJGlobal.Projects[index]
+				// Projects which haven't started by the time the build is cancelled or times out aren't started at all
+				if err := getContextError(options.ctx, "build"); err != nil {
+					results[index] = projectBuildResult{Name: rjProject.Name, Err: err}
+					continue
+				}
 
-	if err != nil {
-		return err
+				output := newPrefixWriter(os.Stdout, &outputLock, fmt.Sprintf("[%s] ", rjProject.Name))
+
+				built, err := rjBuildLogged(rjInfo, rjProject, projectRoot, options, output)
+
+				output.Flush()
+
+				results[index] = projectBuildResult{Name: rjProject.Name, Built: built, Err: err}
+			}
+		}()
 	}
 
-	//Equivalent to "push therileyjohnson/{tag}"
-	runRobPushArgs := []string{
-		"push", fmt.Sprintf("therileyjohnson/rob:%s", tag),
+	for index := range rjInfo.RJGlobal.Projects {
+		projectIndexes <- index
 	}
 
-	cmd = exec.Command("docker", runRobPushArgs...)
+	close(projectIndexes)
+	waitGroup.Wait()
 
-	cmd.Stdout = os.Stdout
+	return results
+}
+
+func rjPushRob(ctx context.Context, tag string, local bool, builder Builder) error {
+	robInstaller := robInstallBuilderRemote
+
+	if local {
+		robInstaller = robInstallBuilderLocal
+	}
+
+	image := fmt.Sprintf("therileyjohnson/rob:%s", tag)
 
-	err = cmd.Start()
+	err := builder.BuildImage(ctx, imageBuildOptions{
+		ContextDir: ".",
+		Dockerfile: robInstaller,
+		Image:      image,
+		Labels:     getRobLabels(image, ""),
+		NoCache:    true,
+		Stderr:     os.Stderr,
+		Stdout:     os.Stdout,
+	})
 
 	if err != nil {
 		return err
 	}
 
-	go manageProcessReaping(cmd, killChannel)
+	return builder.Push(ctx, image, os.Stdout)
+}
 
-	err = cmd.Wait()
+// runShellCommand runs the command through the shell of the host in the directory provided
+func runShellCommand(ctx context.Context, command, dir string, env []string, output io.Writer) error {
+	var cmd *exec.Cmd
 
-	// Indicates that 'manageProcessReaping' can exit
-	killChannel <- RJSignal{}
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
 
-	return err
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	return runCommand(ctx, cmd, nil)
 }
 
-func runServer(projectRoot string) (int, error) {
+// runServer runs the webserver in the project root until it exits or the context is done, returning
+// its exit code
+func runServer(ctx context.Context, projectRoot string) (int, error) {
 	absRoot, err := filepath.Abs(projectRoot)
 
 	if err != nil {
@@ -1084,26 +2045,79 @@ func runServer(projectRoot string) (int, error) {
 
 	cmd.Stdout = os.Stdout
 
-	err = cmd.Start()
+	err = runCommand(ctx, cmd, nil)
 
-	if err != nil {
+	if exitErr, ok := err.(*errCommandExitCode); ok {
+		return exitErr.ExitCode, err
+	} else if err != nil {
 		return 1, err
 	}
 
-	err = cmd.Wait()
+	return 0, nil
+}
+
+// stageProjectRelease builds the project into a staging directory, or restores it from the artifact
+// cache, and publishes the staged output as a new release in the project's site path, reporting
+// whether it was restored; projects without a local path are built remotely
+func stageProjectRelease(rjProject RJProject, rjLocalProject *RJLocalProject, localPath, projectRoot string, release RJRelease, options buildOptions, record *buildRecord, output io.Writer) (bool, error) {
+	key := getArtifactKey(release)
+
+	stagingPath, err := prepareStaging(projectRoot, rjProject.ID)
+
+	if err != nil {
+		return false, errors.Wrap(err, "problem preparing the staging directory")
+	}
+
+	restored := !options.force && restoreProjectArtifact(rjProject, key, stagingPath, options, output)
+
+	if !restored {
+		if localPath == "" {
+			err = buildProjectRemotely(rjProject, release.BuildCommit, stagingPath, options, record, output)
+		} else {
+			err = buildProjectLocally(rjProject, localPath, stagingPath, options, record, output)
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if err = checkStaging(stagingPath); err != nil {
+			return false, err
+		}
+	}
+
+	// Restored artifacts are checked as well since the project's checks can have changed since
+	failures, err := runBuildChecks(rjProject.Checks, stagingPath)
 
 	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok {
-			return exitError.Sys().(syscall.WaitStatus).ExitStatus(), err
+		return false, errors.Wrap(err, "problem checking the build output")
+	}
+
+	if len(failures) != 0 {
+		for _, failure := range failures {
+			fmt.Fprintf(output, "Build check failed for Project '%s': %s.\n", rjProject.Name, failure)
 		}
-		return 1, err
+
+		record.Checks = failures
+
+		return false, newErrBuildRejected(failures)
 	}
 
-	return 0, err
+	if !restored {
+		storeProjectArtifact(rjProject, key, stagingPath, options, output)
+	}
+
+	// Artifacts are cached before the assets are processed so that changes to the asset stages of
+	// the project apply to restored artifacts as well
+	if err = processAssets(options.ctx, rjProject, stagingPath, output); err != nil {
+		return false, errors.Wrap(err, "problem processing the assets of the build output")
+	}
+
+	return restored, publishRelease(projectRoot, rjProject, rjLocalProject, release, options.keepReleases)
 }
 
-func syncronizeLocal(project RJProject, localProject RJLocalProject) (bool, error) {
-	localProjectIsSynced, err := localProjectSynced(localProject.Path, project.URL, project.Name)
+func syncronizeLocal(ctx context.Context, project RJProject, localProject RJLocalProject) (bool, error) {
+	localProjectIsSynced, err := localProjectSynced(ctx, localProject.Path, project.URL, project.Name)
 
 	if err != nil {
 		return false, err
@@ -1125,7 +2139,11 @@ func syncronizeLocal(project RJProject, localProject RJLocalProject) (bool, erro
 		return false, err
 	}
 
-	err = workingTree.Pull(&git.PullOptions{RemoteName: "origin"})
+	err = workingTree.PullContext(ctx, &git.PullOptions{RemoteName: "origin"})
+
+	if ctxErr := getContextError(ctx, "git pull"); err != nil && ctxErr != nil {
+		return false, ctxErr
+	}
 
 	if err != nil {
 		return false, errors.Wrapf(err, "problem pulling to local repo for project '%s'", project.Name)
@@ -1134,32 +2152,179 @@ func syncronizeLocal(project RJProject, localProject RJLocalProject) (bool, erro
 	return true, nil
 }
 
-func writeUpdate(rootPath string, rjInfo RJInfo) error {
-	rjGlobalFile, err := os.Create(path.Join(rootPath, "RJglobal.json"))
+// validateRjInfo returns a description of every structural problem found in RJglobal and RJlocal
+func validateRjInfo(rootPath string, rjInfo *RJInfo) []string {
+	problems := make([]string, 0)
 
-	defer rjGlobalFile.Close()
+	absRoot, err := filepath.Abs(rootPath)
 
 	if err != nil {
-		return err
+		return append(problems, fmt.Sprintf("could not get absolute path to project root: %s", err))
 	}
 
-	if err = json.NewEncoder(rjGlobalFile).Encode(&rjInfo.RJGlobal); err != nil {
-		return err
+	projectIDs := make(map[string]string)
+	projectURLs := make(map[string]string)
+	sitePaths := make([]RJProject, 0)
+
+	if rjInfo.RJGlobal.SiteURL != "" {
+		if err := checkSiteURL(rjInfo.RJGlobal.SiteURL); err != nil {
+			problems = append(problems, fmt.Sprintf("RJglobal has an %s", err))
+		}
 	}
 
-	rjLocalFile, err := os.Create(path.Join(rootPath, "RJlocal.json"))
+	if err := checkRootBuild(rjInfo.RJGlobal.RootBuild); err != nil {
+		problems = append(problems, fmt.Sprintf("RJglobal's webserver build is invalid: %s", err))
+	}
 
-	defer rjLocalFile.Close()
+	for _, rjProject := range rjInfo.RJGlobal.Projects {
+		if rjProject.ID == "" {
+			problems = append(problems, fmt.Sprintf("Project '%s' does not have an ID", rjProject.Name))
+		} else if otherName, exists := projectIDs[rjProject.ID]; exists {
+			problems = append(problems, fmt.Sprintf("Project '%s' has the same ID '%s' as Project '%s'", rjProject.Name, rjProject.ID, otherName))
+		} else {
+			projectIDs[rjProject.ID] = rjProject.Name
+		}
 
-	if err != nil {
-		return err
+		if _, err := transport.NewEndpoint(rjProject.URL); err != nil || rjProject.URL == "" {
+			problems = append(problems, fmt.Sprintf("Project '%s' has an invalid git remote URL '%s'", rjProject.Name, rjProject.URL))
+		} else if otherName, exists := projectURLs[rjProject.URL]; exists {
+			problems = append(problems, fmt.Sprintf("Project '%s' has the same URL '%s' as Project '%s'", rjProject.Name, rjProject.URL, otherName))
+		} else {
+			projectURLs[rjProject.URL] = rjProject.Name
+		}
+
+		if cleanPath := path.Clean(filepath.ToSlash(rjProject.Dockerfile)); rjProject.Dockerfile != "" && (path.IsAbs(cleanPath) || cleanPath == "." || cleanPath == ".." || strings.HasPrefix(cleanPath, "../")) {
+			problems = append(problems, fmt.Sprintf("Project '%s' has a Dockerfile template '%s' which is not inside of the project", rjProject.Name, rjProject.Dockerfile))
+		}
+
+		if err := checkAssetStages(rjProject.AssetStages); err != nil {
+			problems = append(problems, fmt.Sprintf("Project '%s' has an %s", rjProject.Name, err))
+		}
+
+		if err := checkBuildChecks(rjProject.Checks); err != nil {
+			problems = append(problems, fmt.Sprintf("Project '%s' has invalid build checks: %s", rjProject.Name, err))
+		}
+
+		if rjProject.Framework != "" {
+			if err := checkFramework(rjProject.Framework); err != nil {
+				problems = append(problems, fmt.Sprintf("Project '%s' has an %s", rjProject.Name, err))
+			}
+		}
+
+		if rjProject.PackageManager != "" {
+			if err := checkPackageManager(rjProject.PackageManager); err != nil {
+				problems = append(problems, fmt.Sprintf("Project '%s' has an %s", rjProject.Name, err))
+			}
+		}
+
+		for index, buildPath := range append([]string{rjProject.OutputDir}, rjProject.InputDirs...) {
+			// A static site is copied as it is, so its output directory can be the project itself
+			if index == 0 && rjProject.Framework == frameworkStatic && path.Clean(filepath.ToSlash(buildPath)) == "." {
+				continue
+			}
+
+			if cleanPath := path.Clean(filepath.ToSlash(buildPath)); buildPath != "" && (path.IsAbs(cleanPath) || cleanPath == "." || cleanPath == ".." || strings.HasPrefix(cleanPath, "../")) {
+				problems = append(problems, fmt.Sprintf("Project '%s' has a build directory '%s' which is not inside of the project", rjProject.Name, buildPath))
+			}
+		}
+
+		for _, hashInput := range rjProject.HashInputs {
+			if _, err := path.Match(filepath.ToSlash(hashInput), ""); err != nil {
+				problems = append(problems, fmt.Sprintf("Project '%s' has an invalid hash input glob '%s'", rjProject.Name, hashInput))
+			}
+		}
+
+		if rjProject.Retry != nil {
+			if rjProject.Retry.Attempts < 0 {
+				problems = append(problems, fmt.Sprintf("Project '%s' has a negative number of retry attempts", rjProject.Name))
+			}
+
+			if _, err := time.ParseDuration(rjProject.Retry.Backoff); rjProject.Retry.Backoff != "" && err != nil {
+				problems = append(problems, fmt.Sprintf("Project '%s' has an invalid retry backoff '%s'", rjProject.Name, rjProject.Retry.Backoff))
+			}
+
+			if err := checkRetryPhases(rjProject.Retry.Phases); err != nil {
+				problems = append(problems, fmt.Sprintf("Project '%s' has an %s", rjProject.Name, err))
+			}
+		}
+
+		for key := range rjProject.BuildEnv {
+			if key == "" || strings.ContainsAny(key, "= \t\n") {
+				problems = append(problems, fmt.Sprintf("Project '%s' has an invalid build environment variable name '%s'", rjProject.Name, key))
+			} else if strings.ContainsAny(rjProject.BuildEnv[key], "\r\n") {
+				problems = append(problems, fmt.Sprintf("Project '%s' has a build environment variable '%s' with a newline, which a Dockerfile can't set", rjProject.Name, key))
+			}
+		}
+
+		if rjProject.SitePath == "" {
+			problems = append(problems, fmt.Sprintf("Project '%s' does not have a site path", rjProject.Name))
+			continue
+		}
+
+		if filepath.IsAbs(rjProject.SitePath) {
+			problems = append(problems, fmt.Sprintf("Project '%s' has an absolute site path '%s', it must be relative to the project root", rjProject.Name, rjProject.SitePath))
+			continue
+		}
+
+		sitePath := filepath.Join(absRoot, rjProject.SitePath)
+
+		if relativePath, err := filepath.Rel(absRoot, sitePath); err != nil || relativePath == "." || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) {
+			problems = append(problems, fmt.Sprintf("Project '%s' has a site path '%s' which is not inside of the project root", rjProject.Name, rjProject.SitePath))
+			continue
+		}
+
+		for _, otherProject := range sitePaths {
+			otherSitePath := otherProject.SitePath
+
+			if sitePath == otherSitePath {
+				problems = append(problems, fmt.Sprintf("Project '%s' shares the site path '%s' with Project '%s'", rjProject.Name, rjProject.SitePath, otherProject.Name))
+			} else if strings.HasPrefix(sitePath, otherSitePath+string(filepath.Separator)) || strings.HasPrefix(otherSitePath, sitePath+string(filepath.Separator)) {
+				problems = append(problems, fmt.Sprintf("Project '%s' has a site path '%s' nested with the site path of Project '%s'", rjProject.Name, rjProject.SitePath, otherProject.Name))
+			}
+		}
+
+		sitePaths = append(sitePaths, RJProject{Name: rjProject.Name, SitePath: sitePath})
 	}
 
-	if err = json.NewEncoder(rjLocalFile).Encode(&rjInfo.RJLocal); err != nil {
-		return err
+	localProjectIDs := make([]string, 0, len(rjInfo.RJLocal.Projects))
+
+	for projectID := range rjInfo.RJLocal.Projects {
+		localProjectIDs = append(localProjectIDs, projectID)
 	}
 
-	return nil
+	sort.Strings(localProjectIDs)
+
+	for _, projectID := range localProjectIDs {
+		rjLocalProject := rjInfo.RJLocal.Projects[projectID]
+
+		if _, exists := projectIDs[projectID]; !exists {
+			problems = append(problems, fmt.Sprintf("Local project '%s' does not exist in RJglobal, run 'rob prune' to remove it", projectID))
+		}
+
+		if rjLocalProject.Path == "" {
+			continue
+		}
+
+		if fileInfo, err := os.Stat(rjLocalProject.Path); err != nil || !fileInfo.IsDir() {
+			problems = append(problems, fmt.Sprintf("Local project '%s' has a path '%s' which is not an existing directory", projectID, rjLocalProject.Path))
+			continue
+		}
+
+		if rjTagBytes, err := ioutil.ReadFile(filepath.Join(rjLocalProject.Path, ".RJtag")); err == nil && string(rjTagBytes) != projectID {
+			problems = append(problems, fmt.Sprintf("Local project '%s' has a .RJtag file at '%s' with the ID '%s'", projectID, rjLocalProject.Path, string(rjTagBytes)))
+		}
+	}
+
+	return problems
+}
+
+// writeUpdate commits RJglobal and RJlocal together, the caller must hold the project root lock
+// acquired through 'getRjInfoLocked'
+func writeUpdate(rootPath string, rjInfo RJInfo) error {
+	return commitRjFiles(rootPath,
+		rjFile{"RJglobal.json", &rjInfo.RJGlobal},
+		rjFile{"RJlocal.json", &rjInfo.RJLocal},
+	)
 }
 
 func writeRjTag(projectID, localPath string) error {
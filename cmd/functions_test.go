@@ -0,0 +1,254 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestProject creates a project root with a local project in it, returning the project root
+// and the project
+func newTestProject(t *testing.T) (string, RJProject) {
+	projectRoot, err := ioutil.TempDir("", "rob-test-")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectPath := filepath.Join(projectRoot, "local", "foo")
+
+	if err = os.MkdirAll(filepath.Join(projectPath, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"package.json": `{"name": "foo", "scripts": {"build": "react-scripts build"}}`,
+		"src/index.js": "console.log('foo')",
+	}
+
+	for name, contents := range files {
+		if err = ioutil.WriteFile(filepath.Join(projectPath, filepath.FromSlash(name)), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return projectRoot, RJProject{ID: "r1j", Name: "foo", SitePath: "projects/foo", URL: "https://github.com/the-rileyj/foo"}
+}
+
+func TestRjBuild(t *testing.T) {
+	testCases := []struct {
+		name          string
+		force         bool
+		previousHash  func(currentHash string) string
+		expectBuilt   bool
+		expectTrigger string
+	}{
+		{
+			name:          "no previous hash",
+			previousHash:  func(currentHash string) string { return "" },
+			expectBuilt:   true,
+			expectTrigger: buildReasonNoPreviousHash,
+		},
+		{
+			name:          "unchanged",
+			previousHash:  func(currentHash string) string { return currentHash },
+			expectBuilt:   false,
+			expectTrigger: buildReasonUnchanged,
+		},
+		{
+			name:          "forced",
+			force:         true,
+			previousHash:  func(currentHash string) string { return currentHash },
+			expectBuilt:   true,
+			expectTrigger: buildReasonForced,
+		},
+		{
+			name:          "changed hash",
+			previousHash:  func(currentHash string) string { return "stale" },
+			expectBuilt:   true,
+			expectTrigger: buildReasonChangedHash,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			projectRoot, rjProject := newTestProject(t)
+			defer os.RemoveAll(projectRoot)
+
+			projectPath := filepath.Join(projectRoot, "local", "foo")
+			currentHash, _, err := hashProject(projectPath, projectRoot, "", rjProject)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rjInfo := &RJInfo{
+				RJGlobal: RJGlobal{Projects: []RJProject{rjProject}},
+				RJLocal: RJLocal{Projects: map[string]RJLocalProject{
+					rjProject.ID: {Path: projectPath, LastBuildHash: testCase.previousHash(currentHash)},
+				}},
+				localLock: new(sync.Mutex),
+			}
+
+			builder := newFakeBuilder()
+			options := buildOptions{builder: builder, ctx: context.Background(), force: testCase.force, jobs: 1, projectRoot: projectRoot}
+			record := &buildRecord{}
+
+			built, err := rjBuild(rjInfo, rjProject, projectRoot, options, record, ioutil.Discard)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if built != testCase.expectBuilt {
+				t.Errorf("expected built to be %t, got %t", testCase.expectBuilt, built)
+			}
+
+			if record.Trigger != testCase.expectTrigger {
+				t.Errorf("expected the trigger '%s', got '%s'", testCase.expectTrigger, record.Trigger)
+			}
+
+			rjLocalProject := rjInfo.RJLocal.Projects[rjProject.ID]
+
+			if !testCase.expectBuilt {
+				if len(builder.Calls) != 0 {
+					t.Errorf("expected no calls to the backend, got %v", builder.Calls)
+				}
+
+				return
+			}
+
+			if len(builder.Calls) != 2 || builder.Calls[0].Method != "BuildImage" || builder.Calls[1].Method != "Run" {
+				t.Errorf("expected an image to be built and run, got %v", builder.Calls)
+			}
+
+			if rjLocalProject.LastBuildHash != currentHash {
+				t.Errorf("expected the last build hash '%s', got '%s'", currentHash, rjLocalProject.LastBuildHash)
+			}
+
+			if _, err = os.Stat(filepath.Join(projectRoot, "projects", "foo", "index.html")); err != nil {
+				t.Errorf("expected the build output in the site path: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheckRootBuildTargets(t *testing.T) {
+	otherArch := "arm64"
+
+	if runtime.GOARCH == otherArch {
+		otherArch = "amd64"
+	}
+
+	testCases := []struct {
+		rootBuild   *RJRootBuild
+		targets     []buildTarget
+		expectError bool
+	}{
+		{nil, []buildTarget{{GoArch: otherArch, GoOS: "linux"}}, false},
+		{&RJRootBuild{}, []buildTarget{{GoArch: otherArch, GoOS: "windows"}}, false},
+		{&RJRootBuild{CGO: true}, []buildTarget{{GoArch: runtime.GOARCH, GoOS: "linux"}}, false},
+		{&RJRootBuild{CGO: true}, []buildTarget{{GoArch: runtime.GOARCH, GoOS: "linux"}, {GoArch: otherArch, GoOS: "linux"}}, true},
+		{&RJRootBuild{CGO: true}, []buildTarget{{GoArch: runtime.GOARCH, GoOS: "windows"}}, true},
+	}
+
+	for _, testCase := range testCases {
+		if err := checkRootBuildTargets(testCase.rootBuild, testCase.targets); (err != nil) != testCase.expectError {
+			t.Errorf("expected an error to be %t for %+v and %v, got '%v'", testCase.expectError, testCase.rootBuild, testCase.targets, err)
+		}
+	}
+}
+
+func TestEnvQuote(t *testing.T) {
+	testCases := []struct {
+		value  string
+		expect string
+	}{
+		{"", `""`},
+		{"https://example.com/app", `"https://example.com/app"`},
+		{"$HOME and ${PATH}", `"\$HOME and \${PATH}"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\path\`, `"C:\\path\\"`},
+		{"tab\there é", "\"tab\there é\""},
+	}
+
+	for _, testCase := range testCases {
+		if quoted := envQuote(testCase.value); quoted != testCase.expect {
+			t.Errorf("expected '%s' to be quoted as '%s', got '%s'", testCase.value, testCase.expect, quoted)
+		}
+	}
+}
+
+func TestHashProject(t *testing.T) {
+	projectRoot, rjProject := newTestProject(t)
+	defer os.RemoveAll(projectRoot)
+
+	projectPath := filepath.Join(projectRoot, "local", "foo")
+
+	writeFile := func(name, contents string) {
+		filePath := filepath.Join(projectPath, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(filePath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(".gitignore", "*.log\n/coverage/\n")
+	writeFile("debug.log", "first")
+	writeFile("coverage/lcov.info", "first")
+	writeFile("node_modules/react/index.js", "first")
+	writeFile("build/index.html", "first")
+
+	hash, hashedFiles, err := hashProject(projectPath, projectRoot, "", rjProject)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{".gitignore", "package.json", "src/index.js"}; strings.Join(hashedFiles, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected the hashed files %v, got %v", expected, hashedFiles)
+	}
+
+	if rehash, _, err := hashProject(projectPath, projectRoot, "", rjProject); err != nil || rehash != hash {
+		t.Fatalf("expected hashing the unchanged project to give '%s' again, got '%s' (%v)", hash, rehash, err)
+	}
+
+	// Ignored files, dependencies and the build output aren't inputs
+	writeFile("debug.log", "second")
+	writeFile("coverage/lcov.info", "second")
+	writeFile("node_modules/react/index.js", "second")
+	writeFile("build/index.html", "second")
+
+	if rehash, _, err := hashProject(projectPath, projectRoot, "", rjProject); err != nil || rehash != hash {
+		t.Fatalf("expected changing files which aren't inputs to keep the hash '%s', got '%s' (%v)", hash, rehash, err)
+	}
+
+	writeFile("src/index.js", "console.log('bar')")
+
+	if rehash, _, err := hashProject(projectPath, projectRoot, "", rjProject); err != nil || rehash == hash {
+		t.Fatalf("expected changing an input to change the hash '%s' (%v)", hash, err)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Removes the images and stopped containers ROB created.",
+	Long: `Removes the images and stopped containers ROB created, found by the labels ROB adds to them when building.
+The newest '--keepLast' images of every image ROB builds (such as a project's build image) are kept so that their cached layers speed up the next build;
+older builds of the same image, which are left behind untagged, are removed along with their intermediate layers.
+The layers of failed builds are found by the 'rob.managed' label the Dockerfile templates set with a LABEL instruction, and are always removed.
+ROB removes the containers it runs once they exit, so the only stopped containers are those left behind when ROB or the container daemon crashed mid-build;
+every one ROB created is removed. Use '--dryRun' to only list what would be removed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, err := cmd.Flags().GetBool("dryRun")
+
+		if err != nil {
+			return err
+		}
+
+		keepLast, err := cmd.Flags().GetInt("keepLast")
+
+		if err != nil {
+			return err
+		}
+
+		if keepLast < 0 {
+			return errors.New("'--keepLast' can not be negative")
+		}
+
+		// The workspace backend is used if there is a workspace
+		rjInfo, err := getRjInfo(projectRootPath)
+
+		if err != nil {
+			rjInfo = nil
+		}
+
+		builder, err := getWorkspaceBuilder(rjInfo)
+
+		if err != nil {
+			return err
+		}
+
+		label := robManagedLabel + "=true"
+
+		// Containers are removed first, images can't be removed while a container uses them
+		containers, err := builder.ListStoppedContainers(label)
+
+		if err != nil {
+			return err
+		}
+
+		images, err := builder.ListImages(label)
+
+		if err != nil {
+			return err
+		}
+
+		action := "Removed"
+
+		if dryRun {
+			action = "Would remove"
+		}
+
+		var removedContainers, removedImages int
+
+		for _, container := range containers {
+			if !dryRun {
+				if err = builder.RemoveContainer(container.ID); err != nil {
+					cmd.Println(errors.Wrapf(err, "problem removing container '%s'", getResourceName(container)))
+					continue
+				}
+			}
+
+			fmt.Printf("%s container %s (%s)\n", action, getResourceName(container), container.Created.Local().Format("2006-01-02 15:04:05"))
+			removedContainers++
+		}
+
+		kept := make(map[string]int)
+
+		// Images are listed newest first, so the first 'keepLast' of each image are kept; images without
+		// the image label are layers of builds which failed before the image was tagged
+		for _, image := range images {
+			if imageName, built := image.Labels[robImageLabel]; built && kept[imageName] < keepLast {
+				kept[imageName]++
+				continue
+			}
+
+			if !dryRun {
+				if err = removeImage(builder, image); err != nil {
+					cmd.Println(errors.Wrapf(err, "problem removing image '%s'", getResourceName(image)))
+					continue
+				}
+			}
+
+			fmt.Printf("%s image %s (%s)\n", action, getResourceName(image), image.Created.Local().Format("2006-01-02 15:04:05"))
+			removedImages++
+		}
+
+		fmt.Printf("%s %d image(s) and %d container(s).\n", action, removedImages, removedContainers)
+
+		return nil
+	},
+}
+
+// getResourceName gets the names of an image or container, or the short form of its ID if it has none
+func getResourceName(resource builderResource) string {
+	if len(resource.Names) != 0 {
+		return strings.Join(resource.Names, ", ")
+	}
+
+	id := strings.TrimPrefix(resource.ID, "sha256:")
+
+	if len(id) > 12 {
+		id = id[:12]
+	}
+
+	return id
+}
+
+// removeImage removes every tag of the image, which removes the image once the last one is gone;
+// untagged images are removed by ID
+func removeImage(builder Builder, image builderResource) error {
+	if len(image.Names) == 0 {
+		return builder.RemoveImage(image.ID)
+	}
+
+	for _, name := range image.Names {
+		if err := builder.RemoveImage(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	gcCmd.Flags().Bool("dryRun", false, "Lists the images and containers which would be removed without removing them.")
+	gcCmd.Flags().Int("keepLast", 1, "The number of the newest images of every image ROB builds to keep.")
+	rootCmd.AddCommand(gcCmd)
+}
@@ -0,0 +1,86 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// hashCmd represents the hash command
+var hashCmd = &cobra.Command{
+	Use:   "hash",
+	Short: "Prints the content hash of the local copy of the project specified, which is what 'build' compares to decide if the project needs rebuilding.",
+	Long: `Prints the content hash of the local copy of the project specified, which is what 'build' compares to decide if the project needs rebuilding.
+The hash covers the relative path, mode and contents of every file in the project except for '.git', 'node_modules', the build output
+and files ignored by '.gitignore'; if the project has hash inputs, only the files matching them are included. The resolved build
+configuration, rendered Dockerfile, site URL and path, asset stages and checks of the project are hashed along with its files.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files, err := cmd.Flags().GetBool("files")
+
+		if err != nil {
+			return err
+		}
+
+		project := strings.TrimSpace(strings.Join(args, " "))
+
+		if project == "" {
+			return errors.New("a project must be specified")
+		}
+
+		rjInfo, err := getRjInfo(projectRootPath)
+
+		if err != nil {
+			return err
+		}
+
+		index := getProjectIndex(project, rjInfo.RJGlobal.Projects)
+
+		if index == -1 {
+			return errors.New("specified project does not exist")
+		}
+
+		rjProject := rjInfo.RJGlobal.Projects[index]
+		rjLocalProject, rjLocalProjectExists := rjInfo.RJLocal.Projects[rjProject.ID]
+
+		if !rjLocalProjectExists || rjLocalProject.Path == "" {
+			return fmt.Errorf("Project '%s' does not exist locally", rjProject.Name)
+		}
+
+		projectHash, hashedFiles, err := hashProject(rjLocalProject.Path, projectRootPath, rjInfo.RJGlobal.SiteURL, rjProject)
+
+		if err != nil {
+			return errors.Wrapf(err, "problem hashing Project '%s'", rjProject.Name)
+		}
+
+		if files {
+			for _, hashedFile := range hashedFiles {
+				cmd.Println(hashedFile)
+			}
+		}
+
+		cmd.Println(projectHash)
+
+		return nil
+	},
+}
+
+func init() {
+	hashCmd.Flags().Bool("files", false, "Lists the files included in the hash before the hash itself.")
+	rootCmd.AddCommand(hashCmd)
+}
@@ -0,0 +1,150 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Lists the build history of either the project specified or of every project and the webserver if no project is specified.",
+	Long: `Lists the build history of either the project specified or of every project and the webserver if no project is specified.
+Every build, including skipped ones, is recorded with what triggered it, whether it was local or remote, the input hash or commit
+it was built from, when it started and finished, its result, the size of its output and its log. The webserver is listed as 'root'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		last, err := cmd.Flags().GetInt("last")
+
+		if err != nil {
+			return err
+		}
+
+		output, err := cmd.Flags().GetString("output")
+
+		if err != nil {
+			return err
+		}
+
+		if output != "text" && output != "json" {
+			return errors.New("'--output' must be either 'text' or 'json'")
+		}
+
+		records, err := readBuildHistory(projectRootPath)
+
+		if err != nil {
+			return err
+		}
+
+		if project := strings.TrimSpace(strings.Join(args, " ")); project != "" {
+			projectID := rootProjectID
+
+			if project != rootProjectID {
+				rjInfo, err := getRjInfo(projectRootPath)
+
+				if err != nil {
+					return err
+				}
+
+				index := getProjectIndex(project, rjInfo.RJGlobal.Projects)
+
+				if index == -1 {
+					return errors.New("specified project does not exist")
+				}
+
+				projectID = rjInfo.RJGlobal.Projects[index].ID
+			}
+
+			projectRecords := make([]buildRecord, 0)
+
+			for _, record := range records {
+				if record.ProjectID == projectID {
+					projectRecords = append(projectRecords, record)
+				}
+			}
+
+			records = projectRecords
+		}
+
+		if last > 0 && len(records) > last {
+			records = records[len(records)-last:]
+		}
+
+		if output == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+
+			return encoder.Encode(records)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		fmt.Fprintln(writer, "PROJECT\tSTARTED\tDURATION\tTRIGGER\tMODE\tBUILT FROM\tRESULT\tSIZE\tLOG")
+
+		for _, record := range records {
+			builtFrom := record.Hash
+
+			if builtFrom == "" {
+				builtFrom = record.Commit
+			}
+
+			if len(builtFrom) > 12 {
+				builtFrom = builtFrom[:12]
+			}
+
+			result := record.Result
+
+			if record.Restored {
+				result += " (cached)"
+			}
+
+			if len(record.Checks) != 0 {
+				result += fmt.Sprintf(" (%d checks failed)", len(record.Checks))
+			}
+
+			if len(record.Retries) != 0 {
+				result += fmt.Sprintf(" (%d retries)", len(record.Retries))
+			}
+
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				record.ProjectName,
+				record.Start.Local().Format("2006-01-02 15:04:05"),
+				record.End.Sub(record.Start).Round(time.Millisecond),
+				record.Trigger,
+				record.Mode,
+				builtFrom,
+				result,
+				formatSize(record.OutputSize),
+				record.LogPath,
+			)
+		}
+
+		return writer.Flush()
+	},
+}
+
+func init() {
+	historyCmd.Flags().Int("last", 0, "Only lists the last N builds.")
+	historyCmd.Flags().StringP("output", "o", "text", "The output format, either 'text' or 'json'.")
+	rootCmd.AddCommand(historyCmd)
+}
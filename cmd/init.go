@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -39,7 +40,19 @@ var initCmd = &cobra.Command{
 			// RJlocal is autogen'd when it can't be found during information retrieval.
 			_, err = getRjInfo(projectRootPath)
 		} else {
-			_, err = initializeGlobal(projectRootPath, force)
+			lock, err := lockProjectRoot(projectRootPath, lockTimeout)
+
+			if err != nil {
+				return err
+			}
+
+			defer lock.Unlock()
+
+			if _, err = initializeGlobal(projectRootPath, force); err != nil {
+				return err
+			}
+
+			return errors.Wrap(ignoreLocalState(projectRootPath), "problem adding the local build state to the project root's .gitignore")
 		}
 
 		return err
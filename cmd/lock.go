@@ -0,0 +1,237 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	rjCommitFile = ".RJcommit"
+	rjLockFile   = ".RJlock"
+)
+
+// rjIgnoredPaths are the files and directories holding the local build state in the project root,
+// which 'rob init' adds to its '.gitignore', see 'ignoreLocalState'
+var rjIgnoredPaths = []string{"/" + rjCommitFile, "/" + rjHistoryFile, "/" + rjLockFile, "/" + rjLogsDir + "/", "/" + rjReleasesDir + "/"}
+
+// errLockHeld is returned by 'acquireLockFile' when another process already holds the lock
+var errLockHeld = errors.New("lock is held by another process")
+
+// rjLock is an advisory lock on the project root, held for the duration of any mutation of RJInfo
+type rjLock struct {
+	file *os.File
+}
+
+// rjFile pairs the name of a file in the project root with the value to be encoded into it
+type rjFile struct {
+	name  string
+	value interface{}
+}
+
+// lockProjectRoot acquires the advisory lock on the project root, retrying until 'timeout' has passed
+// if another ROB instance holds it; interrupted commits are recovered once the lock is acquired
+func lockProjectRoot(rootPath string, timeout time.Duration) (*rjLock, error) {
+	if _, err := os.Stat(rootPath); err != nil {
+		return nil, errors.New("path to project root does not exist")
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		lockFile, err := acquireLockFile(filepath.Join(rootPath, rjLockFile))
+
+		if err == nil {
+			if err = recoverCommit(rootPath); err != nil {
+				lockFile.Close()
+				return nil, errors.Wrap(err, "problem recovering an interrupted update of RJglobal/RJlocal")
+			}
+
+			return &rjLock{lockFile}, nil
+		}
+
+		if err != errLockHeld {
+			return nil, errors.Wrap(err, "problem locking the project root")
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, newErrWorkspaceBusy(rootPath, timeout)
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// ignoreLocalState appends the paths of the local build state which the project root's '.gitignore'
+// doesn't list yet to it, creating it if it doesn't exist
+func ignoreLocalState(rootPath string) error {
+	gitignorePath := filepath.Join(rootPath, ".gitignore")
+
+	gitignoreBytes, err := ioutil.ReadFile(gitignorePath)
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	ignoredLines := make(map[string]bool)
+
+	for _, line := range strings.Split(string(gitignoreBytes), "\n") {
+		ignoredLines[strings.TrimSpace(line)] = true
+	}
+
+	missingPaths := make([]string, 0, len(rjIgnoredPaths))
+
+	for _, ignoredPath := range rjIgnoredPaths {
+		if !ignoredLines[ignoredPath] {
+			missingPaths = append(missingPaths, ignoredPath)
+		}
+	}
+
+	if len(missingPaths) == 0 {
+		return nil
+	}
+
+	var addition strings.Builder
+
+	if len(gitignoreBytes) != 0 && !strings.HasSuffix(string(gitignoreBytes), "\n") {
+		addition.WriteString("\n")
+	}
+
+	addition.WriteString("# Local build state of ROB\n")
+	addition.WriteString(strings.Join(missingPaths, "\n") + "\n")
+
+	gitignoreFile, err := os.OpenFile(gitignorePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = gitignoreFile.WriteString(addition.String()); err != nil {
+		gitignoreFile.Close()
+		return err
+	}
+
+	return gitignoreFile.Close()
+}
+
+// Unlock releases the advisory lock on the project root
+func (lock *rjLock) Unlock() error {
+	if lock == nil || lock.file == nil {
+		return nil
+	}
+
+	err := lock.file.Close()
+	lock.file = nil
+
+	return err
+}
+
+// commitRjFiles atomically replaces the files in the project root with the encoded values provided;
+// every value is written and synced to a temporary file, a commit marker listing the files is
+// written, and only then are the temporary files renamed over the originals so that an interrupted
+// commit can be rolled forward by 'recoverCommit'
+func commitRjFiles(rootPath string, files ...rjFile) error {
+	names := make([]string, 0, len(files))
+
+	for _, file := range files {
+		fileBytes, err := json.Marshal(file.value)
+
+		if err != nil {
+			return errors.Wrapf(err, "problem encoding %s", file.name)
+		}
+
+		if err = writeFileSynced(filepath.Join(rootPath, file.name+".tmp"), append(fileBytes, '\n')); err != nil {
+			return errors.Wrapf(err, "problem writing %s", file.name)
+		}
+
+		names = append(names, file.name)
+	}
+
+	commitPath := filepath.Join(rootPath, rjCommitFile)
+
+	if err := writeFileSynced(commitPath+".tmp", []byte(strings.Join(names, "\n"))); err != nil {
+		return errors.Wrap(err, "problem writing commit marker")
+	}
+
+	if err := os.Rename(commitPath+".tmp", commitPath); err != nil {
+		return errors.Wrap(err, "problem writing commit marker")
+	}
+
+	return recoverCommit(rootPath)
+}
+
+// recoverCommit finishes a commit whose marker was written, otherwise it discards temporary files
+// left behind by a commit which never got that far
+func recoverCommit(rootPath string) error {
+	commitPath := filepath.Join(rootPath, rjCommitFile)
+
+	commitBytes, err := ioutil.ReadFile(commitPath)
+
+	if os.IsNotExist(err) {
+		os.Remove(commitPath + ".tmp")
+
+		for _, name := range []string{"RJglobal.json", "RJlocal.json"} {
+			os.Remove(filepath.Join(rootPath, name+".tmp"))
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, name := range strings.Split(string(commitBytes), "\n") {
+		if name == "" {
+			continue
+		}
+
+		err = os.Rename(filepath.Join(rootPath, name+".tmp"), filepath.Join(rootPath, name))
+
+		// Already renamed if the temporary file is gone
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Remove(commitPath)
+}
+
+func writeFileSynced(filePath string, contents []byte) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(contents); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
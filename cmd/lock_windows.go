@@ -0,0 +1,52 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+const errorSharingViolation syscall.Errno = 32
+
+// acquireLockFile opens the lock file at the path provided without sharing it, which is as
+// exclusive as a lock gets on Windows; the handle is released when closed or the process exits
+func acquireLockFile(lockPath string) (*os.File, error) {
+	lockPathPointer, err := syscall.UTF16PtrFromString(lockPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := syscall.CreateFile(
+		lockPathPointer,
+		syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		0,
+		nil,
+		syscall.OPEN_ALWAYS,
+		syscall.FILE_ATTRIBUTE_NORMAL,
+		0,
+	)
+
+	if err != nil {
+		if err == errorSharingViolation {
+			return nil, errLockHeld
+		}
+
+		return nil, err
+	}
+
+	return os.NewFile(uintptr(handle), lockPath), nil
+}
@@ -0,0 +1,177 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Prints the build log of the project specified, the latest build by default.",
+	Long: `Prints the build log of the project specified, the latest build by default.
+Every build writes its full output to a log under '.RJlogs' in the project root; use '--list' to see the builds which have logs,
+'--build' to pick one of them and '--follow' to keep printing the log of a build which is still running until it finishes.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		build, err := cmd.Flags().GetInt("build")
+
+		if err != nil {
+			return err
+		}
+
+		follow, err := cmd.Flags().GetBool("follow")
+
+		if err != nil {
+			return err
+		}
+
+		list, err := cmd.Flags().GetBool("list")
+
+		if err != nil {
+			return err
+		}
+
+		project := strings.TrimSpace(strings.Join(args, " "))
+
+		if project == "" {
+			return errors.New("a project must be specified")
+		}
+
+		// The workspace isn't locked so that the log of a running build can be followed
+		rjInfo, err := getRjInfo(projectRootPath)
+
+		if err != nil {
+			return err
+		}
+
+		index := getProjectIndex(project, rjInfo.RJGlobal.Projects)
+
+		if index == -1 {
+			return errors.New("specified project does not exist")
+		}
+
+		rjProject := rjInfo.RJGlobal.Projects[index]
+
+		buildLogs, err := listBuildLogs(projectRootPath, rjProject.ID)
+
+		if err != nil {
+			return err
+		}
+
+		if list {
+			for _, buildLog := range buildLogs {
+				fmt.Println(buildLog.Path)
+			}
+
+			return nil
+		}
+
+		if len(buildLogs) == 0 {
+			return fmt.Errorf("Project '%s' does not have any build logs", rjProject.Name)
+		}
+
+		logPath := buildLogs[len(buildLogs)-1].Path
+
+		if build != 0 {
+			logPath = ""
+
+			for _, buildLog := range buildLogs {
+				if buildLog.Number == build {
+					logPath = buildLog.Path
+				}
+			}
+
+			if logPath == "" {
+				return fmt.Errorf("Project '%s' does not have a log for build %d", rjProject.Name, build)
+			}
+		}
+
+		return printBuildLog(logPath, filepath.Join(projectRootPath, rjLockFile), follow, os.Stdout)
+	},
+}
+
+// printBuildLog copies the build log to 'output'; when following, the log keeps being read until
+// the footer written at the end of a build shows up, or until the workspace lock at 'lockPath' is
+// free once the log is read to its end, since a build which was killed never writes the footer
+func printBuildLog(logPath, lockPath string, follow bool, output io.Writer) error {
+	logFile, err := os.Open(logPath)
+
+	if err != nil {
+		return err
+	}
+
+	defer logFile.Close()
+
+	if !follow {
+		_, err = io.Copy(output, logFile)
+		return err
+	}
+
+	var lastLine string
+	buffer := make([]byte, 32*1024)
+	building := true
+
+	for {
+		read, err := logFile.Read(buffer)
+
+		if read != 0 {
+			output.Write(buffer[:read])
+
+			lines := strings.Split(lastLine+string(buffer[:read]), "\n")
+			lastLine = lines[len(lines)-1]
+
+			for _, line := range lines[:len(lines)-1] {
+				if strings.HasPrefix(line, buildLogFooter) {
+					return nil
+				}
+			}
+		}
+
+		if err == io.EOF && !building {
+			return nil
+		} else if err == io.EOF {
+			// The log is read to its end once more after the lock is free, for whatever the build
+			// wrote before releasing it
+			lockFile, err := acquireLockFile(lockPath)
+
+			if err == nil {
+				lockFile.Close()
+				building = false
+			} else if err == errLockHeld {
+				time.Sleep(250 * time.Millisecond)
+			} else {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func init() {
+	logsCmd.Flags().Int("build", 0, "The number of the build to print the log of.")
+	logsCmd.Flags().BoolP("follow", "f", false, "Keeps printing the log as it is written until the build finishes.")
+	logsCmd.Flags().Bool("list", false, "Lists the build logs of the project instead of printing one.")
+	rootCmd.AddCommand(logsCmd)
+}
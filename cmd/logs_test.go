@@ -0,0 +1,89 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPrintBuildLogFollow(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "rob-logs")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(tempDir)
+
+	logPath, lockPath := filepath.Join(tempDir, "1.log"), filepath.Join(tempDir, rjLockFile)
+
+	if err = ioutil.WriteFile(logPath, []byte("ROB: build 1 of Project 'test' started\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A build which was killed before writing the footer is followed until the workspace is free
+	lockFile, err := acquireLockFile(lockPath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.AfterFunc(500*time.Millisecond, func() {
+		logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+
+		if err == nil {
+			logFile.WriteString("killed\n")
+			logFile.Close()
+		}
+
+		lockFile.Close()
+	})
+
+	var output bytes.Buffer
+
+	if err = printBuildLog(logPath, lockPath, true, &output); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "ROB: build 1 of Project 'test' started\nkilled\n"; output.String() != expected {
+		t.Errorf("expected the log '%s', got '%s'", expected, output.String())
+	}
+
+	// A finished build is printed up to its footer, even while the workspace is locked
+	if err = ioutil.WriteFile(logPath, []byte("output\n"+buildLogFooter+" at now: succeeded\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if lockFile, err = acquireLockFile(lockPath); err != nil {
+		t.Fatal(err)
+	}
+
+	defer lockFile.Close()
+
+	output.Reset()
+
+	if err = printBuildLog(logPath, lockPath, true, &output); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "output\n" + buildLogFooter + " at now: succeeded\n"; output.String() != expected {
+		t.Errorf("expected the log '%s', got '%s'", expected, output.String())
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrades RJglobal and RJlocal to the latest schema version, backing up the originals first.",
+	Long: `Upgrades RJglobal and RJlocal to the latest schema version, backing up the originals first.
+Older files are upgraded in memory whenever they are loaded, this command writes the upgrade back to disk.
+Backups are written next to the originals as '{file}.{timestamp}.bak'.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
+
+		if err != nil {
+			return err
+		}
+
+		defer lock.Unlock()
+
+		outdated := 0
+		timestamp := time.Now().Format("20060102150405")
+
+		for _, schemaFile := range []struct {
+			name          string
+			migrations    []rjMigration
+			latestVersion int
+		}{
+			{"RJglobal.json", rjGlobalMigrations, rjGlobalSchemaVersion},
+			{"RJlocal.json", rjLocalMigrations, rjLocalSchemaVersion},
+		} {
+			filePath := filepath.Join(projectRootPath, schemaFile.name)
+
+			fileBytes, err := ioutil.ReadFile(filePath)
+
+			if err != nil {
+				return err
+			}
+
+			_, version, err := migrateRjFile(schemaFile.name, fileBytes, schemaFile.migrations)
+
+			if err != nil {
+				return err
+			}
+
+			if version == schemaFile.latestVersion {
+				cmd.Printf("%s is already at schema version %d.\n", schemaFile.name, version)
+				continue
+			}
+
+			backupPath := fmt.Sprintf("%s.%s.bak", filePath, timestamp)
+
+			if err = writeFileSynced(backupPath, fileBytes); err != nil {
+				return errors.Wrapf(err, "problem backing up %s", schemaFile.name)
+			}
+
+			cmd.Printf("%s will be migrated from schema version %d to %d, backed up to %s.\n", schemaFile.name, version, schemaFile.latestVersion, backupPath)
+			outdated++
+		}
+
+		if outdated == 0 {
+			return nil
+		}
+
+		return writeUpdate(projectRootPath, *rjInfo)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,174 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	planActionBuild = "build"
+	planActionError = "error"
+	planActionSkip  = "skip"
+)
+
+// planBuild prints what 'rob build' would do for the project specified, or for every project if
+// none is, in the output format provided; RJglobal and RJlocal are only read, so a missing RJlocal
+// is treated as empty rather than initialized
+func planBuild(projectRoot, project string, options buildOptions, output string, writer io.Writer) error {
+	rjGlobal, err := getRjGlobal(projectRoot)
+
+	if err != nil {
+		return err
+	}
+
+	rjLocal, err := getRjLocal(projectRoot)
+
+	if _, notFound := err.(*errRjFileNotFound); notFound {
+		rjLocal, err = RJLocal{Projects: make(map[string]RJLocalProject)}, nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	rjInfo := &RJInfo{RJGlobal: rjGlobal, RJLocal: rjLocal}
+	options.projectRoot, options.siteURL = projectRoot, rjGlobal.SiteURL
+	rjProjects := rjGlobal.Projects
+
+	if project != "" {
+		index := getProjectIndex(project, rjGlobal.Projects)
+
+		if index == -1 {
+			return errors.New("project specified does not exist")
+		}
+
+		rjProjects = rjGlobal.Projects[index : index+1]
+	}
+
+	plans := make([]projectBuildPlan, 0, len(rjProjects))
+	failed := 0
+
+	for _, rjProject := range rjProjects {
+		plan := planProjectBuild(rjInfo, rjProject, options)
+
+		if plan.Action == planActionError {
+			failed++
+		}
+
+		plans = append(plans, plan)
+	}
+
+	if output == "json" {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+
+		if err = encoder.Encode(plans); err != nil {
+			return err
+		}
+	} else {
+		printBuildPlan(writer, plans)
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("could not plan the build of %d of %d projects", failed, len(plans))
+	}
+
+	return nil
+}
+
+// planProjectBuild works out what 'rjBuild' would do for the project without building anything,
+// writing to RJlocal or talking to the container backend
+func planProjectBuild(rjInfo *RJInfo, rjProject RJProject, options buildOptions) projectBuildPlan {
+	rjLocalProject := rjInfo.RJLocal.Projects[rjProject.ID]
+
+	plan := projectBuildPlan{ProjectID: rjProject.ID, ProjectName: rjProject.Name, Reasons: make([]string, 0)}
+
+	if rjLocalProject.Path != "" {
+		plan.Mode = buildModeLocal
+		plan.Previous = rjLocalProject.LastBuildHash
+
+		currentHash, _, err := hashProject(rjLocalProject.Path, options.projectRoot, options.siteURL, rjProject)
+
+		if err != nil {
+			plan.Action = planActionError
+			plan.Error = fmt.Sprintf("problem hashing Project '%s': %s", rjProject.Name, err)
+			return plan
+		}
+
+		plan.Current = currentHash
+		plan.Reasons = append(plan.Reasons, getBuildReason(currentHash, plan.Previous, false, options.force))
+	} else {
+		plan.Mode = buildModeRemote
+		plan.Previous = rjLocalProject.LastBuildCommit
+
+		remoteCommit, err := getRemoteProjectCommit(options.ctx, rjProject.URL, getBuildRef(rjProject, options))
+
+		if err != nil {
+			plan.Action = planActionError
+			plan.Error = fmt.Sprintf("problem getting the remote hash for Project '%s': %s", rjProject.Name, err)
+			return plan
+		}
+
+		buildDigest, err := getBuildDigest(rjProject, "", options.projectRoot, options.siteURL)
+
+		if err != nil {
+			plan.Action = planActionError
+			plan.Error = fmt.Sprintf("problem getting the build digest of Project '%s': %s", rjProject.Name, err)
+			return plan
+		}
+
+		plan.Current = remoteCommit
+		plan.Reasons = append(plan.Reasons, getRemoteBuildReason(remoteCommit, buildDigest, rjLocalProject, options.force))
+	}
+
+	if plan.Reasons[0] == buildReasonUnchanged {
+		plan.Action = planActionSkip
+		return plan
+	}
+
+	plan.Action = planActionBuild
+
+	if plan.Mode == buildModeRemote && options.native {
+		plan.Reasons = append([]string{buildReasonNoLocalPathClone}, plan.Reasons...)
+	} else if plan.Mode == buildModeRemote {
+		plan.Reasons = append([]string{buildReasonNoLocalPath}, plan.Reasons...)
+	}
+
+	return plan
+}
+
+// printBuildPlan prints a table of the build plans
+func printBuildPlan(writer io.Writer, plans []projectBuildPlan) {
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tabWriter, "PROJECT\tACTION\tMODE\tREASONS")
+
+	for _, plan := range plans {
+		if plan.Error != "" {
+			fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%s\n", plan.ProjectName, plan.Action, plan.Mode, plan.Error)
+		} else {
+			fmt.Fprintf(tabWriter, "%s\t%s\t%s\t%s\n", plan.ProjectName, plan.Action, plan.Mode, strings.Join(plan.Reasons, ", "))
+		}
+	}
+
+	tabWriter.Flush()
+}
@@ -25,12 +25,14 @@ var pruneCmd = &cobra.Command{
 	Use:   "prune",
 	Short: "Deletes all local projects not found in RJglobal.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		rjInfo, err := getRjInfo(projectRootPath)
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
 
 		if err != nil {
 			return err
 		}
 
+		defer lock.Unlock()
+
 		pruned := pruneLocal(rjInfo)
 
 		if pruned == 0 {
@@ -33,7 +33,23 @@ var pushCmd = &cobra.Command{
 
 		tag := strings.TrimSpace(strings.Join(args, " "))
 
-		return rjPushRob(tag, local)
+		// The workspace backend is used if there is a workspace
+		rjInfo, err := getRjInfo(projectRootPath)
+
+		if err != nil {
+			rjInfo = nil
+		}
+
+		builder, err := getWorkspaceBuilder(rjInfo)
+
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newCommandContext(0)
+		defer cancel()
+
+		return rjPushRob(ctx, tag, local, builder)
 	},
 }
 
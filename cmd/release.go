@@ -0,0 +1,186 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultKeepReleases = 5
+	rjReleasesDir       = ".RJreleases"
+)
+
+// getReleasesPath gets the directory the releases of the project are kept in
+func getReleasesPath(projectRoot, projectID string) (string, error) {
+	absRoot, err := filepath.Abs(projectRoot)
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(absRoot, rjReleasesDir, projectID), nil
+}
+
+// prepareStaging creates an empty staging directory for the project to be built into, returning
+// its absolute path
+func prepareStaging(projectRoot, projectID string) (string, error) {
+	releasesPath, err := getReleasesPath(projectRoot, projectID)
+
+	if err != nil {
+		return "", err
+	}
+
+	stagingPath := filepath.Join(releasesPath, "staging")
+
+	if err = os.RemoveAll(stagingPath); err != nil {
+		return "", err
+	}
+
+	return stagingPath, os.MkdirAll(stagingPath, os.ModePerm)
+}
+
+// checkStaging checks that a build left something in the staging directory to be released
+func checkStaging(stagingPath string) error {
+	stagedFiles, err := ioutil.ReadDir(stagingPath)
+
+	if err != nil {
+		return err
+	}
+
+	if len(stagedFiles) == 0 {
+		return errors.New("the build did not output any files")
+	}
+
+	return nil
+}
+
+// publishRelease turns the staging directory into the next numbered release of the project and
+// swaps it into the project's site path, then removes all but the newest 'keepReleases' releases
+func publishRelease(projectRoot string, rjProject RJProject, rjLocalProject *RJLocalProject, release RJRelease, keepReleases int) error {
+	releasesPath, err := getReleasesPath(projectRoot, rjProject.ID)
+
+	if err != nil {
+		return err
+	}
+
+	release.Created = time.Now().UTC()
+	release.Number = 1
+
+	for _, previousRelease := range rjLocalProject.Releases {
+		if previousRelease.Number >= release.Number {
+			release.Number = previousRelease.Number + 1
+		}
+	}
+
+	releasePath := filepath.Join(releasesPath, strconv.Itoa(release.Number))
+
+	// A release left behind by an interrupted build was never recorded, so it can be replaced
+	if err = os.RemoveAll(releasePath); err != nil {
+		return err
+	}
+
+	if err = os.Rename(filepath.Join(releasesPath, "staging"), releasePath); err != nil {
+		return err
+	}
+
+	if err = activateRelease(projectRoot, rjProject, release.Number); err != nil {
+		os.RemoveAll(releasePath)
+		return err
+	}
+
+	rjLocalProject.Release = release.Number
+	rjLocalProject.Releases = append(rjLocalProject.Releases, release)
+
+	for len(rjLocalProject.Releases) > keepReleases {
+		os.RemoveAll(filepath.Join(releasesPath, strconv.Itoa(rjLocalProject.Releases[0].Number)))
+		rjLocalProject.Releases = rjLocalProject.Releases[1:]
+	}
+
+	return nil
+}
+
+// activateRelease copies the release provided next to the project's site path and swaps the two,
+// so the site path always holds either the old or the new release in full
+func activateRelease(projectRoot string, rjProject RJProject, number int) error {
+	absRoot, err := filepath.Abs(projectRoot)
+
+	if err != nil {
+		return err
+	}
+
+	releasesPath := filepath.Join(absRoot, rjReleasesDir, rjProject.ID)
+	livePath := filepath.Join(releasesPath, "live")
+	sitePath := filepath.Join(absRoot, rjProject.SitePath)
+
+	if err = os.RemoveAll(livePath); err != nil {
+		return err
+	}
+
+	if err = copyDirectory(filepath.Join(releasesPath, strconv.Itoa(number)), livePath); err != nil {
+		return errors.Wrapf(err, "problem copying release %d", number)
+	}
+
+	defer os.RemoveAll(livePath)
+
+	if _, err = os.Stat(sitePath); os.IsNotExist(err) {
+		if err = os.MkdirAll(filepath.Dir(sitePath), os.ModePerm); err != nil {
+			return err
+		}
+
+		return os.Rename(livePath, sitePath)
+	}
+
+	if err = exchangeDirectories(livePath, sitePath); err != nil {
+		return errors.Wrapf(err, "problem swapping release %d into the site path '%s'", number, rjProject.SitePath)
+	}
+
+	return nil
+}
+
+// swapDirectories swaps two directories with a rename through a temporary path, which is used
+// where the directories can't be exchanged atomically
+func swapDirectories(firstPath, secondPath string) error {
+	swapPath := secondPath + ".rob-swap"
+
+	if err := os.Rename(secondPath, swapPath); err != nil {
+		return err
+	}
+
+	if err := os.Rename(firstPath, secondPath); err != nil {
+		os.Rename(swapPath, secondPath)
+		return err
+	}
+
+	return os.Rename(swapPath, firstPath)
+}
+
+// findRelease finds the release with the number provided
+func findRelease(rjLocalProject RJLocalProject, number int) (RJRelease, error) {
+	for _, release := range rjLocalProject.Releases {
+		if release.Number == number {
+			return release, nil
+		}
+	}
+
+	return RJRelease{}, fmt.Errorf("release %d does not exist", number)
+}
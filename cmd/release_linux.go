@@ -0,0 +1,34 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package cmd
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// exchangeDirectories atomically exchanges two directories with renameat2, falling back to
+// renames when the kernel or filesystem doesn't support exchanging
+func exchangeDirectories(firstPath, secondPath string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, firstPath, unix.AT_FDCWD, secondPath, unix.RENAME_EXCHANGE)
+
+	if err == unix.ENOSYS || err == unix.EINVAL {
+		return swapDirectories(firstPath, secondPath)
+	}
+
+	return err
+}
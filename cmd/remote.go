@@ -0,0 +1,162 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+// cloneRemoteProject clones the project to 'cloneDir' and checks out the commit provided, along
+// with the submodules of that commit
+func cloneRemoteProject(ctx context.Context, rjProject RJProject, remoteCommit, cloneDir string) error {
+	auth, err := getRemoteAuth(rjProject.URL)
+
+	if err != nil {
+		return err
+	}
+
+	repository, err := git.PlainCloneContext(ctx, cloneDir, false, &git.CloneOptions{
+		Auth:       auth,
+		NoCheckout: true,
+		Tags:       git.AllTags,
+		URL:        rjProject.URL,
+	})
+
+	if ctxErr := getContextError(ctx, "git clone"); err != nil && ctxErr != nil {
+		return ctxErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repository.Worktree()
+
+	if err != nil {
+		return err
+	}
+
+	if err = worktree.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(remoteCommit)}); err != nil {
+		return errors.Wrapf(err, "problem checking out commit '%s'", remoteCommit)
+	}
+
+	// Submodules are updated after checking out the commit so that they match it
+	submodules, err := worktree.Submodules()
+
+	if err != nil {
+		return err
+	}
+
+	err = submodules.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Auth:              auth,
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+	})
+
+	if ctxErr := getContextError(ctx, "git submodule update"); err != nil && ctxErr != nil {
+		return ctxErr
+	}
+
+	return errors.Wrap(err, "problem updating submodules")
+}
+
+// getRemoteAuth gets the credentials for the git remote provided; SSH remotes use the key from
+// '--sshKey' (or ROB_SSH_KEY) if there is one and the SSH agent otherwise, and HTTP(S) remotes use
+// the token from '--gitToken' (or ROB_GIT_TOKEN) if there is one
+func getRemoteAuth(projectURL string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(projectURL)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "'%s' is not a valid git remote", projectURL)
+	}
+
+	switch endpoint.Protocol {
+	case "ssh":
+		keyPath := sshKeyFlag
+
+		if keyPath == "" {
+			keyPath = os.Getenv("ROB_SSH_KEY")
+		}
+
+		if keyPath == "" {
+			return nil, nil
+		}
+
+		user := endpoint.User
+
+		if user == "" {
+			user = "git"
+		}
+
+		auth, err := gitssh.NewPublicKeysFromFile(user, keyPath, os.Getenv("ROB_SSH_KEY_PASSWORD"))
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "problem reading the SSH key '%s'", keyPath)
+		}
+
+		return auth, nil
+	case "http", "https":
+		token := gitTokenFlag
+
+		if token == "" {
+			token = os.Getenv("ROB_GIT_TOKEN")
+		}
+
+		if token == "" {
+			return nil, nil
+		}
+
+		// Hosts accept any username along with an access token, but some need a particular one
+		username := os.Getenv("ROB_GIT_USERNAME")
+
+		if username == "" {
+			username = endpoint.User
+		}
+
+		if username == "" {
+			username = "rob"
+		}
+
+		return &githttp.BasicAuth{Username: username, Password: token}, nil
+	}
+
+	return nil, nil
+}
+
+// getRemoteName gets the name of the repository at the git remote provided
+func getRemoteName(projectURL string) string {
+	if endpoint, err := transport.NewEndpoint(projectURL); err == nil && endpoint.Path != "" {
+		projectURL = endpoint.Path
+	}
+
+	return strings.TrimSuffix(path.Base(strings.TrimRight(projectURL, "/")), ".git")
+}
+
+// isGithubURL checks if the remote is on GitHub, which is the only host descriptions can be fetched from
+func isGithubURL(projectURL string) bool {
+	endpoint, err := transport.NewEndpoint(projectURL)
+
+	return err == nil && strings.EqualFold(endpoint.Host, "github.com")
+}
@@ -0,0 +1,132 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runGit runs git in the directory provided, returning its trimmed output
+func runGit(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=rob", "-c", "user.email=rob@example.com"}, args...)...)
+
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, output)
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+// commitFile writes the file in the working copy provided and commits it, returning the commit
+func commitFile(t *testing.T, workDir, name, contents string) string {
+	if err := ioutil.WriteFile(filepath.Join(workDir, name), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runGit(t, workDir, "add", name)
+	runGit(t, workDir, "commit", "-m", "Update "+name)
+
+	return runGit(t, workDir, "rev-parse", "HEAD")
+}
+
+func TestCloneRemoteProject(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	testDir, err := ioutil.TempDir("", "rob-test-")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(testDir)
+
+	remotePath := filepath.Join(testDir, "remote.git")
+	workDir := filepath.Join(testDir, "work")
+
+	runGit(t, testDir, "init", "--bare", remotePath)
+	runGit(t, testDir, "clone", remotePath, workDir)
+
+	taggedCommit := commitFile(t, workDir, "package.json", `{"name": "v1"}`)
+
+	runGit(t, workDir, "tag", "-a", "v1", "-m", "v1")
+
+	untaggedCommit := commitFile(t, workDir, "package.json", `{"name": "untagged"}`)
+	headCommit := commitFile(t, workDir, "package.json", `{"name": "v2"}`)
+
+	runGit(t, workDir, "tag", "v2")
+	runGit(t, workDir, "push", "--follow-tags", "origin", "HEAD", "v2", "HEAD:refs/heads/feature")
+
+	testCases := []struct {
+		name           string
+		ref            string
+		expectCommit   string
+		expectContents string
+	}{
+		{name: "default branch", expectCommit: headCommit, expectContents: `{"name": "v2"}`},
+		{name: "annotated tag", ref: "v1", expectCommit: taggedCommit, expectContents: `{"name": "v1"}`},
+		{name: "branch", ref: "feature", expectCommit: headCommit, expectContents: `{"name": "v2"}`},
+		{name: "lightweight tag", ref: "v2", expectCommit: headCommit, expectContents: `{"name": "v2"}`},
+		{name: "commit", ref: taggedCommit, expectCommit: taggedCommit, expectContents: `{"name": "v1"}`},
+		{name: "commit without a reference", ref: untaggedCommit, expectCommit: untaggedCommit, expectContents: `{"name": "untagged"}`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			remoteCommit, err := getRemoteProjectCommit(ctx, remotePath, testCase.ref)
+
+			if err != nil {
+				t.Fatalf("unexpected error getting the remote commit: %s", err)
+			}
+
+			if remoteCommit != testCase.expectCommit {
+				t.Fatalf("expected the commit '%s', got '%s'", testCase.expectCommit, remoteCommit)
+			}
+
+			cloneDir, err := ioutil.TempDir(testDir, "clone-")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err = cloneRemoteProject(ctx, RJProject{Name: "foo", URL: remotePath}, remoteCommit, cloneDir); err != nil {
+				t.Fatalf("unexpected error cloning: %s", err)
+			}
+
+			contents, err := ioutil.ReadFile(filepath.Join(cloneDir, "package.json"))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(contents) != testCase.expectContents {
+				t.Errorf("expected the contents '%s', got '%s'", testCase.expectContents, contents)
+			}
+		})
+	}
+}
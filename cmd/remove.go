@@ -42,12 +42,14 @@ to quickly create a Cobra application.`,
 			return err
 		}
 
-		rjInfo, err := getRjInfo(projectRootPath)
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
 
 		if err != nil {
 			return err
 		}
 
+		defer lock.Unlock()
+
 		project := strings.TrimSpace(strings.Join(args, " "))
 
 		if project == "" {
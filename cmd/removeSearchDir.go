@@ -25,12 +25,14 @@ var removeSearchDirCmd = &cobra.Command{
 	Use:   "searchDir",
 	Short: "Removes a search directory.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		rjInfo, err := getRjInfo(projectRootPath)
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
 
 		if err != nil {
 			return err
 		}
 
+		defer lock.Unlock()
+
 		var update bool
 
 		for _, parseString := range args {
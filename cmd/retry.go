@@ -0,0 +1,146 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRetryAttempts = 1
+	defaultRetryBackoff  = 5 * time.Second
+
+	retryPhaseBuild   = "build"
+	retryPhaseClone   = "clone"
+	retryPhaseInstall = "install"
+)
+
+// defaultRetryPhases are the phases retried when a retry policy doesn't name any, the phases which
+// usually fail because of the network rather than the project
+var defaultRetryPhases = []string{retryPhaseClone, retryPhaseInstall}
+
+// retryPolicy is the retry policy of a project with the '--retry*' flags and defaults applied
+type retryPolicy struct {
+	attempts int
+	backoff  time.Duration
+	phases   []string
+}
+
+// checkRetryPhases returns an error if any of the phases isn't one of the phases of a build
+func checkRetryPhases(phases []string) error {
+	for _, phase := range phases {
+		switch phase {
+		case retryPhaseBuild, retryPhaseClone, retryPhaseInstall:
+		default:
+			return fmt.Errorf("unknown retry phase '%s', expected '%s', '%s' or '%s'", phase, retryPhaseClone, retryPhaseInstall, retryPhaseBuild)
+		}
+	}
+
+	return nil
+}
+
+// getRetryPolicy gets the retry policy a project is built with; the '--retry*' flags of 'build'
+// override the project's retry policy, which overrides the defaults of a single attempt with a
+// backoff of 'defaultRetryBackoff' for the 'defaultRetryPhases'
+func getRetryPolicy(rjProject RJProject, options buildOptions) (retryPolicy, error) {
+	policy := retryPolicy{attempts: defaultRetryAttempts, backoff: defaultRetryBackoff, phases: defaultRetryPhases}
+
+	if rjProject.Retry != nil {
+		if rjProject.Retry.Attempts > 0 {
+			policy.attempts = rjProject.Retry.Attempts
+		}
+
+		if rjProject.Retry.Backoff != "" {
+			backoff, err := time.ParseDuration(rjProject.Retry.Backoff)
+
+			if err != nil {
+				return policy, errors.Wrapf(err, "Project '%s' has an invalid retry backoff", rjProject.Name)
+			}
+
+			policy.backoff = backoff
+		}
+
+		if len(rjProject.Retry.Phases) != 0 {
+			policy.phases = rjProject.Retry.Phases
+		}
+	}
+
+	if options.retryAttempts > 0 {
+		policy.attempts = options.retryAttempts
+	}
+
+	if options.retryBackoff > 0 {
+		policy.backoff = options.retryBackoff
+	}
+
+	if len(options.retryPhases) != 0 {
+		policy.phases = options.retryPhases
+	}
+
+	return policy, nil
+}
+
+// retries checks if the phase is retried by the policy
+func (policy retryPolicy) retries(phase string) bool {
+	for _, retriedPhase := range policy.phases {
+		if retriedPhase == phase {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runPhase runs a phase of a build, retrying it if it fails and the policy retries the phase; the
+// backoff doubles after every failed attempt, and every retry is written to 'output' and added to
+// the build record. Only the failed phase is run again, so an image build which is retried reuses
+// the layers cached by the attempts before it. Cancellation and timeouts are never retried
+func runPhase(ctx context.Context, policy retryPolicy, phase string, record *buildRecord, output io.Writer, run func() error) error {
+	attempts := 1
+
+	if policy.retries(phase) {
+		attempts = policy.attempts
+	}
+
+	backoff := policy.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := run()
+
+		if err == nil || attempt >= attempts || getContextError(ctx, phase) != nil {
+			return err
+		}
+
+		fmt.Fprintf(output, "ROB: %s failed on attempt %d of %d, retrying in %s: %s\n", phase, attempt, attempts, backoff, strings.TrimSpace(err.Error()))
+
+		if record != nil {
+			record.Retries = append(record.Retries, buildRetry{Attempt: attempt, Error: err.Error(), Phase: phase})
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return getContextError(ctx, phase)
+		}
+
+		backoff *= 2
+	}
+}
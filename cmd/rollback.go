@@ -0,0 +1,134 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restores an earlier release of the project specified to its site path, the release before the current one by default.",
+	Long: `Restores an earlier release of the project specified to its site path, the release before the current one by default.
+The release is swapped into the site path in the same way as a build and RJlocal is updated to match it, so the next build
+compares against the hash (or remote commit) the release was built from. Use '--list' to see the releases which are kept.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		list, err := cmd.Flags().GetBool("list")
+
+		if err != nil {
+			return err
+		}
+
+		to, err := cmd.Flags().GetInt("to")
+
+		if err != nil {
+			return err
+		}
+
+		project := strings.TrimSpace(strings.Join(args, " "))
+
+		if project == "" {
+			return errors.New("a project must be specified")
+		}
+
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
+
+		if err != nil {
+			return err
+		}
+
+		defer lock.Unlock()
+
+		index := getProjectIndex(project, rjInfo.RJGlobal.Projects)
+
+		if index == -1 {
+			return errors.New("specified project does not exist")
+		}
+
+		rjProject := rjInfo.RJGlobal.Projects[index]
+		rjLocalProject := rjInfo.RJLocal.Projects[rjProject.ID]
+
+		if list {
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+			fmt.Fprintln(writer, "RELEASE\tCREATED\tBUILT FROM\t")
+
+			for _, release := range rjLocalProject.Releases {
+				builtFrom, current := release.BuildHash, ""
+
+				if builtFrom == "" {
+					builtFrom = "commit " + release.BuildCommit
+				}
+
+				if release.Number == rjLocalProject.Release {
+					current = "(current)"
+				}
+
+				fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", release.Number, release.Created.Local().Format("2006-01-02 15:04:05"), builtFrom, current)
+			}
+
+			return writer.Flush()
+		}
+
+		if to == 0 {
+			for _, release := range rjLocalProject.Releases {
+				if release.Number < rjLocalProject.Release && release.Number > to {
+					to = release.Number
+				}
+			}
+
+			if to == 0 {
+				return fmt.Errorf("Project '%s' does not have a release before release %d to roll back to", rjProject.Name, rjLocalProject.Release)
+			}
+		}
+
+		release, err := findRelease(rjLocalProject, to)
+
+		if err != nil {
+			return errors.Wrapf(err, "problem rolling back Project '%s'", rjProject.Name)
+		}
+
+		if release.Number == rjLocalProject.Release {
+			return fmt.Errorf("release %d is already in the site path of Project '%s'", release.Number, rjProject.Name)
+		}
+
+		if err = activateRelease(projectRootPath, rjProject, release.Number); err != nil {
+			return errors.Wrapf(err, "problem rolling back Project '%s'", rjProject.Name)
+		}
+
+		rjLocalProject.LastBuildCommit = release.BuildCommit
+		rjLocalProject.LastBuildConfig = release.BuildConfig
+		rjLocalProject.LastBuildHash = release.BuildHash
+		rjLocalProject.Release = release.Number
+		rjInfo.RJLocal.Projects[rjProject.ID] = rjLocalProject
+
+		cmd.Printf("Project '%s' rolled back to release %d.\n", rjProject.Name, release.Number)
+
+		return writeUpdate(projectRootPath, *rjInfo)
+	},
+}
+
+func init() {
+	rollbackCmd.Flags().Bool("list", false, "Lists the releases of the project instead of rolling back.")
+	rollbackCmd.Flags().Int("to", 0, "The number of the release to roll back to.")
+	rootCmd.AddCommand(rollbackCmd)
+}
@@ -17,18 +17,34 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 const (
+	currentNodeVersion    = "20-alpine" // Used by default for the frameworks and package managers node 8 can't run
+	defaultBuildScript    = "build"
+	defaultGoVersion      = "1.22-alpine"
+	defaultInstallCommand = "npm install"
+	defaultNodeVersion    = "8.11.3-alpine"
+	defaultOutputDir      = "build"
 	reactLocalDockerfile  = "react-local-build.dockerfile"
 	reactRemoteDockerfile = "react-remote-build.dockerfile"
 	rjServer              = "RJserver"
 	rjURL                 = "https://therileyjohnson.com"
+	rootDockerfile        = "server-build.dockerfile"
 )
 
+// defaultInputDirs are the directories copied into the local build image when a project doesn't specify its own
+var defaultInputDirs = []string{"src", "public"}
+
+var backendFlag string
+var cacheDirFlag string
+var gitTokenFlag string
+var lockTimeout time.Duration
 var projectRootPath string
+var sshKeyFlag string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -55,6 +71,11 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVarP(&projectRootPath, "projectRoot", "r", "./", "Path to the project root.")
+	rootCmd.PersistentFlags().StringVar(&sshKeyFlag, "sshKey", "", "The private key used to clone projects from SSH git remotes, defaults to the ROB_SSH_KEY environment variable and then the SSH agent.")
+	rootCmd.PersistentFlags().StringVar(&backendFlag, "backend", "", "The container backend to use ('docker' or 'podman'), overrides the backend set for the workspace in RJlocal.")
+	rootCmd.PersistentFlags().StringVar(&cacheDirFlag, "cacheDir", "", "The directory build artifacts are cached in (default 'rob/artifacts' in the user cache directory).")
+	rootCmd.PersistentFlags().StringVar(&gitTokenFlag, "gitToken", "", "The access token used to clone projects from HTTP(S) git remotes, defaults to the ROB_GIT_TOKEN environment variable.")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lockTimeout", 0, "How long to wait for another ROB instance to release the project root before giving up with a 'workspace busy' error.")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -22,17 +22,29 @@ import (
 var runCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Runs the webserver in the project root in management mode (Checks program return code, and checks for update on status code 9).",
+	Long: `Runs the webserver in the project root in management mode (Checks program return code, and checks for update on status code 9).
+The webserver is killed if ROB is interrupted or once '--timeout' has passed.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		var err error
+		timeout, err := cmd.Flags().GetDuration("timeout")
+
+		if err != nil {
+			cmd.Println(err)
+			return
+		}
+
+		ctx, cancel := newCommandContext(timeout)
+		defer cancel()
+
 		var statusCode int
 
 		for statusCode == 0 || statusCode == 9 {
-			statusCode, err = runServer(projectRootPath)
+			statusCode, err = runServer(ctx, projectRootPath)
 		}
 		cmd.Println(err)
 	},
 }
 
 func init() {
+	runCmd.Flags().Duration("timeout", 0, "How long the webserver may run before it is stopped, 0 for no limit.")
 	rootCmd.AddCommand(runCmd)
 }
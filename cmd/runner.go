@@ -0,0 +1,125 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// newCommandContext returns the context every process, container and git operation of a command
+// runs under; it is cancelled if ROB is interrupted and times out once 'timeout' has passed unless
+// 'timeout' is 0. The CancelFunc must be called once the command is done to stop listening for signals
+func newCommandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	interruptCtx, interrupt := context.WithCancel(context.Background())
+	ctx, cancelTimeout := interruptCtx, context.CancelFunc(func() {})
+
+	if timeout > 0 {
+		ctx, cancelTimeout = context.WithTimeout(interruptCtx, timeout)
+	}
+
+	signals := make(chan os.Signal, 1)
+
+	signal.Notify(signals,
+		syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+		syscall.SIGQUIT,
+	)
+
+	go func() {
+		select {
+		case <-signals:
+			interrupt()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancelTimeout()
+		interrupt()
+	}
+}
+
+// getContextError returns an errCommandCancelled or errCommandTimedOut for the operation described
+// if the context is done, otherwise nil
+func getContextError(ctx context.Context, operation string) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return newErrCommandCancelled(operation)
+	case context.DeadlineExceeded:
+		return newErrCommandTimedOut(operation)
+	}
+
+	return nil
+}
+
+// getCommandName describes the command by its executable and first argument, i.e. "docker build"
+func getCommandName(cmd *exec.Cmd) string {
+	if len(cmd.Args) > 1 {
+		return filepath.Base(cmd.Args[0]) + " " + cmd.Args[1]
+	}
+
+	return filepath.Base(cmd.Path)
+}
+
+// runCommand runs the command until it exits or the context is done, in which case 'stop' is
+// called (if provided) to stop anything the command started outside of its own process tree, such
+// as a named container, and the command is killed along with its child processes. A non-zero exit
+// is returned as an errCommandExitCode, cancellation as an errCommandCancelled and a timeout as
+// an errCommandTimedOut
+func runCommand(ctx context.Context, cmd *exec.Cmd, stop func()) error {
+	name := getCommandName(cmd)
+
+	if err := getContextError(ctx, name); err != nil {
+		return err
+	}
+
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	waitResult := make(chan error, 1)
+
+	go func() {
+		waitResult <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-waitResult:
+		if exitError, ok := err.(*exec.ExitError); ok {
+			return newErrCommandExitCode(name, exitError.ExitCode())
+		}
+
+		return err
+	case <-ctx.Done():
+		if stop != nil {
+			stop()
+		}
+
+		killProcessTree(cmd)
+		<-waitResult
+
+		return getContextError(ctx, name)
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// rjMigration upgrades the decoded contents of an RJ file by a single schema version
+type rjMigration func(contents map[string]interface{}) error
+
+// rjGlobalMigrations[n] upgrades RJglobal from schema version n to n+1, append only
+var rjGlobalMigrations = []rjMigration{
+	migrateGlobalV0,
+}
+
+// rjLocalMigrations[n] upgrades RJlocal from schema version n to n+1, append only
+var rjLocalMigrations = []rjMigration{
+	migrateLocalV0,
+}
+
+var (
+	rjGlobalSchemaVersion = len(rjGlobalMigrations)
+	rjLocalSchemaVersion  = len(rjLocalMigrations)
+)
+
+// migrateGlobalV0 stamps unversioned RJglobal files, which otherwise already match version 1
+func migrateGlobalV0(contents map[string]interface{}) error {
+	if contents["projects"] == nil {
+		contents["projects"] = []interface{}{}
+	}
+
+	return nil
+}
+
+// migrateLocalV0 moves the 'CurrentProjectHash' field of the original RJlocal layout over to
+// 'LastBuildCommit', which replaced it
+func migrateLocalV0(contents map[string]interface{}) error {
+	if contents["searchPaths"] == nil {
+		contents["searchPaths"] = []interface{}{}
+	}
+
+	projects, ok := contents["projects"].(map[string]interface{})
+
+	if !ok {
+		if contents["projects"] != nil {
+			return errors.New("'projects' is not an object")
+		}
+
+		contents["projects"] = map[string]interface{}{}
+		return nil
+	}
+
+	for projectID, project := range projects {
+		localProject, ok := project.(map[string]interface{})
+
+		if !ok {
+			return errors.Errorf("local project '%s' is not an object", projectID)
+		}
+
+		currentProjectHash, exists := localProject["CurrentProjectHash"]
+
+		if !exists {
+			continue
+		}
+
+		if lastBuildCommit, _ := localProject["LastBuildCommit"].(string); lastBuildCommit == "" {
+			localProject["LastBuildCommit"] = currentProjectHash
+		}
+
+		delete(localProject, "CurrentProjectHash")
+	}
+
+	return nil
+}
+
+// migrateRjFile runs every migration needed to bring the contents of an RJ file up to the latest
+// schema version, returning the upgraded contents along with the version the file was at
+func migrateRjFile(fileName string, fileBytes []byte, migrations []rjMigration) ([]byte, int, error) {
+	contents := make(map[string]interface{})
+
+	decoder := json.NewDecoder(bytes.NewReader(fileBytes))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&contents); err != nil {
+		return nil, 0, errors.Wrapf(err, "problem decoding %s", fileName)
+	}
+
+	version, err := getSchemaVersion(contents)
+
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "problem reading the schema version of %s", fileName)
+	}
+
+	if version > len(migrations) {
+		return nil, version, newErrRjSchemaTooNew(fileName, version, len(migrations))
+	}
+
+	for from := version; from < len(migrations); from++ {
+		if err = migrations[from](contents); err != nil {
+			return nil, version, errors.Wrapf(err, "problem migrating %s from schema version %d to %d", fileName, from, from+1)
+		}
+	}
+
+	contents["schemaVersion"] = len(migrations)
+
+	migratedBytes, err := json.Marshal(contents)
+
+	return migratedBytes, version, err
+}
+
+func getSchemaVersion(contents map[string]interface{}) (int, error) {
+	version, exists := contents["schemaVersion"]
+
+	if !exists || version == nil {
+		return 0, nil
+	}
+
+	number, ok := version.(json.Number)
+
+	if !ok {
+		return 0, errors.New("'schemaVersion' is not a number")
+	}
+
+	parsed, err := number.Int64()
+
+	if err != nil || parsed < 0 {
+		return 0, errors.New("'schemaVersion' is not a positive integer")
+	}
+
+	return int(parsed), nil
+}
+
+//=======================================================================
+// Unknown field preservation, so fields from other versions of ROB survive a round trip
+
+// jsonFieldNames lists the lower cased JSON names of the fields of the struct type provided
+func jsonFieldNames(structType reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		names[strings.ToLower(name)] = true
+	}
+
+	return names
+}
+
+// marshalWithUnknown encodes the structure provided with the unknown fields merged back in
+func marshalWithUnknown(structure interface{}, unknownFields map[string]json.RawMessage) ([]byte, error) {
+	structureBytes, err := json.Marshal(structure)
+
+	if err != nil || len(unknownFields) == 0 {
+		return structureBytes, err
+	}
+
+	fields := make(map[string]json.RawMessage)
+
+	if err = json.Unmarshal(structureBytes, &fields); err != nil {
+		return nil, err
+	}
+
+	for name, value := range unknownFields {
+		if _, exists := fields[name]; !exists {
+			fields[name] = value
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// unmarshalWithUnknown decodes the data into the struct pointed to by 'structure' and returns every
+// field in the data which the struct does not declare
+func unmarshalWithUnknown(data []byte, structure interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, structure); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]json.RawMessage)
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	knownFields := jsonFieldNames(reflect.TypeOf(structure).Elem())
+
+	for name := range fields {
+		if knownFields[strings.ToLower(name)] {
+			delete(fields, name)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return fields, nil
+}
+
+// MarshalJSON keeps fields unknown to this version of ROB
+func (rjGlobal RJGlobal) MarshalJSON() ([]byte, error) {
+	type rjGlobalFields RJGlobal
+	return marshalWithUnknown(rjGlobalFields(rjGlobal), rjGlobal.unknownFields)
+}
+
+// UnmarshalJSON keeps fields unknown to this version of ROB
+func (rjGlobal *RJGlobal) UnmarshalJSON(data []byte) error {
+	type rjGlobalFields RJGlobal
+	fields := rjGlobalFields(*rjGlobal)
+
+	unknownFields, err := unmarshalWithUnknown(data, &fields)
+
+	if err != nil {
+		return err
+	}
+
+	*rjGlobal = RJGlobal(fields)
+	rjGlobal.unknownFields = unknownFields
+
+	return nil
+}
+
+// MarshalJSON keeps fields unknown to this version of ROB
+func (rjLocal RJLocal) MarshalJSON() ([]byte, error) {
+	type rjLocalFields RJLocal
+	return marshalWithUnknown(rjLocalFields(rjLocal), rjLocal.unknownFields)
+}
+
+// UnmarshalJSON keeps fields unknown to this version of ROB
+func (rjLocal *RJLocal) UnmarshalJSON(data []byte) error {
+	type rjLocalFields RJLocal
+	fields := rjLocalFields(*rjLocal)
+
+	unknownFields, err := unmarshalWithUnknown(data, &fields)
+
+	if err != nil {
+		return err
+	}
+
+	*rjLocal = RJLocal(fields)
+	rjLocal.unknownFields = unknownFields
+
+	return nil
+}
+
+// MarshalJSON keeps fields unknown to this version of ROB
+func (rjLocalProject RJLocalProject) MarshalJSON() ([]byte, error) {
+	type rjLocalProjectFields RJLocalProject
+	return marshalWithUnknown(rjLocalProjectFields(rjLocalProject), rjLocalProject.unknownFields)
+}
+
+// UnmarshalJSON keeps fields unknown to this version of ROB
+func (rjLocalProject *RJLocalProject) UnmarshalJSON(data []byte) error {
+	type rjLocalProjectFields RJLocalProject
+	fields := rjLocalProjectFields(*rjLocalProject)
+
+	unknownFields, err := unmarshalWithUnknown(data, &fields)
+
+	if err != nil {
+		return err
+	}
+
+	*rjLocalProject = RJLocalProject(fields)
+	rjLocalProject.unknownFields = unknownFields
+
+	return nil
+}
+
+// MarshalJSON keeps fields unknown to this version of ROB
+func (rjProject RJProject) MarshalJSON() ([]byte, error) {
+	type rjProjectFields RJProject
+	return marshalWithUnknown(rjProjectFields(rjProject), rjProject.unknownFields)
+}
+
+// UnmarshalJSON keeps fields unknown to this version of ROB
+func (rjProject *RJProject) UnmarshalJSON(data []byte) error {
+	type rjProjectFields RJProject
+	fields := rjProjectFields(*rjProject)
+
+	unknownFields, err := unmarshalWithUnknown(data, &fields)
+
+	if err != nil {
+		return err
+	}
+
+	*rjProject = RJProject(fields)
+	rjProject.unknownFields = unknownFields
+
+	return nil
+}
@@ -15,40 +15,125 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"strings"
 	"sync"
+	"time"
 )
 
 //==================
 // Local Use Structs
 
-// RJInfo is for centralizing global and local information for the program's use
+// RJInfo is for centralizing global and local information for the program's use, it is never
+// encoded itself since RJGlobal and RJLocal are written to separate files
 type RJInfo struct {
-	RJGlobal
-	RJLocal
-	token string
+	RJGlobal  `json:"-"`
+	RJLocal   `json:"-"`
+	localLock *sync.Mutex
+	token     string
+}
+
+// getLocalProject is safe to call while projects are being built concurrently
+func (rjInfo *RJInfo) getLocalProject(projectID string) (RJLocalProject, bool) {
+	rjInfo.localLock.Lock()
+	defer rjInfo.localLock.Unlock()
+
+	rjLocalProject, exists := rjInfo.RJLocal.Projects[projectID]
+
+	return rjLocalProject, exists
+}
+
+// setLocalProject is safe to call while projects are being built concurrently
+func (rjInfo *RJInfo) setLocalProject(projectID string, rjLocalProject RJLocalProject) {
+	rjInfo.localLock.Lock()
+	defer rjInfo.localLock.Unlock()
+
+	rjInfo.RJLocal.Projects[projectID] = rjLocalProject
 }
 
 // RJGlobal is for storing global information about projects and the project root URL, committed
 type RJGlobal struct {
-	Projects []RJProject `json:"projects"`
-	URL      string      `json:"url"`
+	Projects      []RJProject  `json:"projects"`
+	RootBuild     *RJRootBuild `json:"rootBuild,omitempty"`
+	SchemaVersion int          `json:"schemaVersion"`
+	SiteURL       string       `json:"siteURL,omitempty"` // Where the site is served, projects are built for being served under it
+	URL           string       `json:"url"`
+
+	unknownFields map[string]json.RawMessage
+}
+
+// RJRootBuild is for storing how the webserver is built, committed
+type RJRootBuild struct {
+	CGO       bool     `json:"cgo,omitempty"`       // Builds with cgo, which needs a C cross compiler for other platforms
+	GoVersion string   `json:"goVersion,omitempty"` // The tag of the golang image the webserver is built with
+	LDFlags   string   `json:"ldflags,omitempty"`   // Passed to the linker after the version and commit stamping flags
+	Tags      []string `json:"tags,omitempty"`
 }
 
 // RJLocalProject is for storing local information about a given project, not committed
 type RJLocalProject struct {
 	Path            string // Used when building from local
 	LastBuildCommit string // Used when building from remote
+	LastBuildConfig string `json:"lastBuildConfig,omitempty"` // The build digest of the last build from remote
 	LastBuildHash   string // Used when building from local
+
+	Release  int         `json:"release,omitempty"`  // The release currently in the site path
+	Releases []RJRelease `json:"releases,omitempty"` // The releases kept for rolling back, oldest first
+
+	unknownFields map[string]json.RawMessage
+}
+
+// RJRelease is a build of a project kept so that the site path can be rolled back to it, not committed
+type RJRelease struct {
+	BuildCommit string    `json:"buildCommit,omitempty"`
+	BuildConfig string    `json:"buildConfig,omitempty"` // The build digest of a release built from remote
+	BuildHash   string    `json:"buildHash,omitempty"`
+	Created     time.Time `json:"created"`
+	Number      int       `json:"number"`
 }
 
 // RJProject is for storing global information about a given project, committed
 type RJProject struct {
-	Description string `json:"description"`
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	SitePath    string `json:"sitePath"`
-	URL         string `json:"url"`
+	AssetStages    []string          `json:"assetStages,omitempty"` // Any of 'gzip', 'brotli', 'manifest' and 'cacheControl'
+	BuildEnv       map[string]string `json:"buildEnv,omitempty"`
+	BuildScript    string            `json:"buildScript,omitempty"`
+	Checks         *RJBuildChecks    `json:"checks,omitempty"`
+	Description    string            `json:"description"`
+	Dockerfile     string            `json:"dockerfile,omitempty"`
+	Framework      string            `json:"framework,omitempty"`
+	HashInputs     []string          `json:"hashInputs,omitempty"`
+	ID             string            `json:"id"`
+	InputDirs      []string          `json:"inputDirs,omitempty"`
+	InstallCommand string            `json:"installCommand,omitempty"`
+	Name           string            `json:"name"`
+	NodeVersion    string            `json:"nodeVersion,omitempty"`
+	OutputDir      string            `json:"outputDir,omitempty"`
+	PackageManager string            `json:"packageManager,omitempty"`
+	Ref            string            `json:"ref,omitempty"`
+	Retry          *RJRetryPolicy    `json:"retry,omitempty"`
+	SitePath       string            `json:"sitePath"`
+	URL            string            `json:"url"`
+
+	unknownFields map[string]json.RawMessage
+}
+
+// RJBuildChecks is for storing the checks a project's build output has to pass to be released, committed
+type RJBuildChecks struct {
+	Forbidden    []string          `json:"forbidden,omitempty"`    // Globs of files which must not be in the output, such as '*.map'
+	MaxFileSize  string            `json:"maxFileSize,omitempty"`  // The maximum size of any single file in the output, such as '500KB'
+	MaxSize      string            `json:"maxSize,omitempty"`      // The maximum total size of the output, such as '5MB'
+	MaxTypeSizes map[string]string `json:"maxTypeSizes,omitempty"` // The maximum combined size of all the files with an extension, such as '.js', rather than of each
+	Required     []string          `json:"required,omitempty"`     // Globs of files which must be in the output, such as 'index.html'
+}
+
+// RJRetryPolicy is for storing how the phases of a project's builds are retried when they fail, committed
+type RJRetryPolicy struct {
+	Attempts int      `json:"attempts,omitempty"` // The maximum number of attempts of each retried phase
+	Backoff  string   `json:"backoff,omitempty"`  // The wait before the first retry as a Go duration, doubled after every retry
+	Phases   []string `json:"phases,omitempty"`   // Any of 'clone', 'install' and 'build'
 }
 
 // RJLocal is for storing local information about projects, the last commit hash of the webserver, and where to start searching for local projects, not committed
@@ -56,6 +141,162 @@ type RJLocal struct {
 	Projects              map[string]RJLocalProject `json:"projects"`
 	SearchPaths           []string                  `json:"searchPaths"`
 	LastRemoteHashOnBuild string                    `json:"lastRemoteHashOnBuild"`
+	Backend               string                    `json:"backend,omitempty"`
+	SchemaVersion         int                       `json:"schemaVersion"`
+
+	unknownFields map[string]json.RawMessage
+}
+
+// reactBuildConfig is the build configuration of a project with defaults filled in, rendered into the React Dockerfiles
+type reactBuildConfig struct {
+	BaseURL        string
+	BuildEnv       map[string]string
+	BuildScript    string
+	Framework      string
+	InputDirs      []string
+	InstallCommand string
+	NodeVersion    string
+	OutputDir      string
+	PackageManager string
+	ScriptArgs     []string
+}
+
+// buildDigestInput is everything besides a project's files that its build output depends on, which
+// is encoded as JSON to get the project's build digest
+type buildDigestInput struct {
+	AssetStages        []string
+	BuildConfig        reactBuildConfig
+	Checks             *RJBuildChecks
+	Dockerfile         string // The rendered Dockerfile, empty for static sites and remote projects' own templates
+	DockerfileTemplate string // The project's own Dockerfile template, if it has one
+	SitePath           string
+}
+
+// reactDockerfileData is what the React Dockerfile templates are rendered with
+type reactDockerfileData struct {
+	reactBuildConfig
+	Project RJProject
+	Remote  bool // Built from a clone of the remote rather than the local project
+}
+
+// rootDockerfileData is what the webserver's Dockerfile template is rendered with
+type rootDockerfileData struct {
+	BuildName  string
+	CGOEnabled bool
+	GoARM      string // The ARM version for 32-bit ARM, otherwise empty
+	GoArch     string
+	GoOS       string
+	GoVersion  string
+	LDFlags    string
+	Tags       string // Comma separated
+}
+
+// buildTarget is a platform the webserver is built for
+type buildTarget struct {
+	GoARM  string
+	GoArch string
+	GoOS   string
+}
+
+func (target buildTarget) String() string {
+	if target.GoARM != "" {
+		return fmt.Sprintf("%s/%s/v%s", target.GoOS, target.GoArch, target.GoARM)
+	}
+
+	return fmt.Sprintf("%s/%s", target.GoOS, target.GoArch)
+}
+
+// suffix is the target as it is appended to the names of executables and images, such as 'linux-arm-v7'
+func (target buildTarget) suffix() string {
+	return strings.Replace(target.String(), "/", "-", -1)
+}
+
+// assetManifest is the manifest of a project's build output written by the 'manifest' asset stage
+type assetManifest struct {
+	Assets map[string]assetManifestEntry `json:"assets"` // By slash-separated path relative to the site path
+}
+
+// assetManifestEntry describes a single file of a project's build output
+type assetManifestEntry struct {
+	BrotliSize int64  `json:"brotliSize,omitempty"` // The size of the '.br' sibling, if there is one
+	GzipSize   int64  `json:"gzipSize,omitempty"`   // The size of the '.gz' sibling, if there is one
+	Integrity  string `json:"integrity"`            // Subresource Integrity hash
+	SHA256     string `json:"sha256"`
+	Size       int64  `json:"size"`
+}
+
+// buildOptions are the options shared by every project built in a single run of 'rob build'
+type buildOptions struct {
+	builder       Builder
+	cacheDir      string
+	ctx           context.Context
+	force         bool
+	jobs          int
+	keepLogs      int
+	keepReleases  int
+	native        bool
+	projectRoot   string
+	ref           string
+	retryAttempts int
+	retryBackoff  time.Duration
+	retryPhases   []string
+	siteURL       string
+}
+
+// buildRecord is a single build in the build history, for a project or the webserver
+type buildRecord struct {
+	Checks      []string     `json:"checks,omitempty"` // The build checks the output failed
+	Commit      string       `json:"commit,omitempty"`
+	End         time.Time    `json:"end"`
+	Error       string       `json:"error,omitempty"`
+	Hash        string       `json:"hash,omitempty"`
+	LogPath     string       `json:"logPath,omitempty"`
+	Mode        string       `json:"mode"`
+	Native      bool         `json:"native,omitempty"`
+	OutputSize  int64        `json:"outputSize"`
+	Previous    string       `json:"previous,omitempty"` // The hash or commit of the build before this one
+	ProjectID   string       `json:"projectId"`
+	ProjectName string       `json:"projectName"`
+	Restored    bool         `json:"restored,omitempty"` // Restored from the artifact cache instead of built
+	Result      string       `json:"result"`
+	Retries     []buildRetry `json:"retries,omitempty"`
+	Start       time.Time    `json:"start"`
+	Trigger     string       `json:"trigger"`
+}
+
+// buildRetry is a failed attempt at a phase of a build which was retried
+type buildRetry struct {
+	Attempt int    `json:"attempt"`
+	Error   string `json:"error"`
+	Phase   string `json:"phase"`
+}
+
+// cachedArtifact is a build artifact in the artifact cache
+type cachedArtifact struct {
+	Created   time.Time
+	Key       string
+	Path      string
+	ProjectID string
+	Size      int64
+}
+
+// projectBuildPlan is what 'rjBuild' would do for a project, as reported by 'rob build --plan'
+type projectBuildPlan struct {
+	Action      string   `json:"action"`
+	Current     string   `json:"current,omitempty"` // The input hash or remote commit the project would be built from
+	Error       string   `json:"error,omitempty"`
+	Mode        string   `json:"mode"`
+	Previous    string   `json:"previous,omitempty"`
+	ProjectID   string   `json:"projectId"`
+	ProjectName string   `json:"projectName"`
+	Reasons     []string `json:"reasons"`
+}
+
+// projectBuildResult is the outcome of building a single project as part of building every project
+type projectBuildResult struct {
+	Built bool
+	Err   error
+	Name  string
 }
 
 type arguments struct {
@@ -68,19 +309,6 @@ type githubToken struct {
 	Token string `json:"token"`
 }
 
-//===========================================================================
-// Signal for sending success signal to processes, satisfies Signal interface
-
-// RJSignal struct for indicating that shell process finished properly and the main process can stop listening for CTRL+C so it can kill that process */
-type RJSignal struct{}
-
-func (rjs RJSignal) String() string {
-	return "RJ"
-}
-
-// Signal satisfies the os.Signal interface
-func (rjs RJSignal) Signal() {}
-
 //===============================
 // For use in mapping directories
 
@@ -233,6 +461,61 @@ type devDependencies map[string]string
 
 type scripts map[string]string
 
+//==========================================================================
+// Writer for prefixing every line of output, used when building concurrently
+
+// prefixWriter buffers partial lines so that the lines of writers sharing the same lock never interleave
+type prefixWriter struct {
+	buffer []byte
+	lock   *sync.Mutex
+	prefix string
+	writer io.Writer
+}
+
+func newPrefixWriter(writer io.Writer, lock *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{lock: lock, prefix: prefix, writer: writer}
+}
+
+// Write passes every complete line along with the prefix prepended
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.buffer = append(pw.buffer, p...)
+
+	lastNewline := strings.LastIndexByte(string(pw.buffer), '\n')
+
+	if lastNewline == -1 {
+		return len(p), nil
+	}
+
+	lines := strings.SplitAfter(string(pw.buffer[:lastNewline+1]), "\n")
+	pw.buffer = append(pw.buffer[:0], pw.buffer[lastNewline+1:]...)
+
+	pw.lock.Lock()
+	defer pw.lock.Unlock()
+
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		if _, err := io.WriteString(pw.writer, pw.prefix+line); err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush passes along any partial line left in the buffer
+func (pw *prefixWriter) Flush() error {
+	if len(pw.buffer) == 0 {
+		return nil
+	}
+
+	_, err := pw.Write([]byte("\n"))
+
+	return err
+}
+
 type dirMapStackItem struct {
 	dirMap
 	previous string
@@ -26,6 +26,12 @@ var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Checks the local git hash against what's in the remote repo and updates either the local project specified by 'project' or all local projects if 'project' is not specified.",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, err := cmd.Flags().GetDuration("timeout")
+
+		if err != nil {
+			return err
+		}
+
 		rjInfo, err := getRjInfo(projectRootPath)
 
 		if err != nil {
@@ -34,10 +40,17 @@ var syncCmd = &cobra.Command{
 
 		project := strings.TrimSpace(strings.Join(args, " "))
 
+		ctx, cancel := newCommandContext(timeout)
+		defer cancel()
+
 		if project == "" {
 			for _, rjProject := range rjInfo.RJGlobal.Projects {
-				if printString, err := handleSyncronizeLocal(&rjProject, &rjInfo.RJLocal); err != nil {
+				if printString, err := handleSyncronizeLocal(ctx, &rjProject, &rjInfo.RJLocal); err != nil {
 					cmd.Println(err)
+
+					if ctxErr := getContextError(ctx, "sync"); ctxErr != nil {
+						return ctxErr
+					}
 				} else {
 					cmd.Println(printString)
 				}
@@ -46,8 +59,12 @@ var syncCmd = &cobra.Command{
 			if index := getProjectIndex(project, rjInfo.RJGlobal.Projects); index != -1 {
 				rjProject := rjInfo.RJGlobal.Projects[index]
 
-				if printString, err := handleSyncronizeLocal(&rjProject, &rjInfo.RJLocal); err != nil {
+				if printString, err := handleSyncronizeLocal(ctx, &rjProject, &rjInfo.RJLocal); err != nil {
 					cmd.Println(err)
+
+					if ctxErr := getContextError(ctx, "sync"); ctxErr != nil {
+						return ctxErr
+					}
 				} else {
 					cmd.Println(printString)
 				}
@@ -61,5 +78,6 @@ var syncCmd = &cobra.Command{
 }
 
 func init() {
+	syncCmd.Flags().Duration("timeout", 0, "How long syncing may take before it is stopped, 0 for no limit.")
 	rootCmd.AddCommand(syncCmd)
 }
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -73,12 +74,85 @@ var updateCmd = &cobra.Command{
 
 		var update bool
 
-		rjInfo, err := getRjInfo(projectRootPath)
+		rjInfo, lock, err := getRjInfoLocked(projectRootPath)
 
 		if err != nil {
 			return err
 		}
 
+		defer lock.Unlock()
+
+		if cmd.Flags().Changed("siteURL") {
+			siteURL, err := cmd.Flags().GetString("siteURL")
+
+			if err != nil {
+				return err
+			}
+
+			if siteURL = strings.TrimSpace(siteURL); siteURL != "" {
+				if err = checkSiteURL(siteURL); err != nil {
+					return err
+				}
+			}
+
+			rjInfo.RJGlobal.SiteURL = siteURL
+
+			update = true
+		}
+
+		if cmd.Flags().Changed("rootCgo") || cmd.Flags().Changed("rootGoVersion") || cmd.Flags().Changed("rootLdflags") || cmd.Flags().Changed("rootTags") {
+			rootBuild := RJRootBuild{}
+
+			if rjInfo.RJGlobal.RootBuild != nil {
+				rootBuild = *rjInfo.RJGlobal.RootBuild
+			}
+
+			if cmd.Flags().Changed("rootCgo") {
+				if rootBuild.CGO, err = cmd.Flags().GetBool("rootCgo"); err != nil {
+					return err
+				}
+			}
+
+			if cmd.Flags().Changed("rootGoVersion") {
+				goVersion, err := cmd.Flags().GetString("rootGoVersion")
+
+				if err != nil {
+					return err
+				}
+
+				rootBuild.GoVersion = strings.TrimSpace(goVersion)
+			}
+
+			if cmd.Flags().Changed("rootLdflags") {
+				ldFlags, err := cmd.Flags().GetString("rootLdflags")
+
+				if err != nil {
+					return err
+				}
+
+				rootBuild.LDFlags = strings.TrimSpace(ldFlags)
+			}
+
+			if cmd.Flags().Changed("rootTags") {
+				if rootBuild.Tags, err = cmd.Flags().GetStringSlice("rootTags"); err != nil {
+					return err
+				}
+			}
+
+			if err = checkRootBuild(&rootBuild); err != nil {
+				return err
+			}
+
+			// An empty configuration is removed so that the webserver is built with the defaults
+			if !rootBuild.CGO && rootBuild.GoVersion == "" && rootBuild.LDFlags == "" && len(rootBuild.Tags) == 0 {
+				rjInfo.RJGlobal.RootBuild = nil
+			} else {
+				rjInfo.RJGlobal.RootBuild = &rootBuild
+			}
+
+			update = true
+		}
+
 		if project != "" {
 			index := getProjectIndex(project, rjInfo.RJGlobal.Projects)
 
@@ -95,6 +169,10 @@ var updateCmd = &cobra.Command{
 
 				update = true
 			} else if token != "" {
+				if !isGithubURL(rjProject.URL) {
+					return errors.New("descriptions can only be fetched for projects on GitHub")
+				}
+
 				newDescription, err := getProjectDescription(rjProject.Name, token)
 
 				if err != nil {
@@ -158,6 +236,18 @@ var updateCmd = &cobra.Command{
 				update = true
 			}
 
+			buildConfigUpdated, err := applyBuildConfigFlags(cmd, &rjProject)
+
+			if err != nil {
+				return err
+			}
+
+			if buildConfigUpdated {
+				rjInfo.RJGlobal.Projects[index] = rjProject
+
+				update = true
+			}
+
 			if update {
 				return writeUpdate(projectRootPath, *rjInfo)
 			}
@@ -169,6 +259,9 @@ var updateCmd = &cobra.Command{
 			var newDescription string
 
 			for index, rjProject := range rjInfo.RJGlobal.Projects {
+				if !isGithubURL(rjProject.URL) {
+					continue
+				}
 
 				newDescription, err = getProjectDescription(rjProject.Name, token)
 
@@ -190,6 +283,10 @@ var updateCmd = &cobra.Command{
 			return writeUpdate(projectRootPath, *rjInfo)
 		}
 
+		if update {
+			return writeUpdate(projectRootPath, *rjInfo)
+		}
+
 		for _, rjProject := range rjInfo.RJGlobal.Projects {
 			created := 0
 			if _, rjLocalExists := rjInfo.RJLocal.Projects[rjProject.ID]; !rjLocalExists {
@@ -209,10 +306,316 @@ var updateCmd = &cobra.Command{
 	},
 }
 
+// addBuildConfigFlags adds the flags for the optional build configuration of a project
+func addBuildConfigFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("assetStages", nil, "Comma separated stages run on the project's build output after it's built, any of 'gzip', 'brotli', 'manifest' and 'cacheControl' (default none).")
+	cmd.Flags().StringArray("buildEnv", nil, "Build-time environment variable for the project in the form 'KEY=VALUE', can be repeated; 'KEY=' removes the variable.")
+	cmd.Flags().String("buildScript", "", fmt.Sprintf("The package.json script which builds the project (default '%s').", defaultBuildScript))
+	cmd.Flags().String("dockerfile", "", "The Dockerfile template the project is built with, relative to the project (default the template in the project root, see 'dockerfiles').")
+	cmd.Flags().StringSlice("forbiddenFiles", nil, "Comma separated globs of files which fail the project's builds if they are in the build output, such as '*.map'; globs without a '/' match files in any directory.")
+	cmd.Flags().String("framework", "", "The framework the project is built with, one of 'cra', 'next', 'static', 'sveltekit', 'vite' or 'vue-cli' (default detected from the project's package.json).")
+	cmd.Flags().StringSlice("hashInputs", nil, "Comma separated globs, relative to the project, of the files which are hashed to decide if the project needs rebuilding (default all files).")
+	cmd.Flags().StringSlice("inputDirs", nil, fmt.Sprintf("Comma separated directories copied into the build image when building locally (default the framework's, otherwise '%s').", strings.Join(defaultInputDirs, ",")))
+	cmd.Flags().String("installCommand", "", fmt.Sprintf("The command which installs the project's dependencies (default the package manager's, otherwise '%s').", defaultInstallCommand))
+	cmd.Flags().String("maxFileSize", "", "The maximum size of any single file in the project's build output, such as '500KB', above which its builds fail.")
+	cmd.Flags().String("maxOutputSize", "", "The maximum total size of the project's build output, such as '5MB', above which its builds fail.")
+	cmd.Flags().StringArray("maxTypeSize", nil, "The maximum combined size of all the files with an extension in the project's build output, not of each file (see '--maxFileSize'), in the form 'EXTENSION=SIZE', such as '.js=1MB', can be repeated; 'EXTENSION=' removes the maximum.")
+	cmd.Flags().String("nodeVersion", "", fmt.Sprintf("The tag of the node image the project is built with (default '%s', '%s' for the frameworks other than Create React App and for pnpm).", defaultNodeVersion, currentNodeVersion))
+	cmd.Flags().String("outputDir", "", fmt.Sprintf("The directory the project is built to, relative to the project (default the framework's, otherwise '%s').", defaultOutputDir))
+	cmd.Flags().String("packageManager", "", "The package manager the project is built with, one of 'npm', 'pnpm' or 'yarn' (default detected from the project's lockfile).")
+	cmd.Flags().StringSlice("requiredFiles", nil, "Comma separated globs of files which fail the project's builds if they aren't in the build output, such as 'index.html'.")
+	cmd.Flags().String("ref", "", "The branch, tag or commit built when the project is built remotely (default the remote's HEAD).")
+	cmd.Flags().Int("retryAttempts", 0, fmt.Sprintf("The maximum number of attempts of each retried phase of the project's builds (default %d).", defaultRetryAttempts))
+	cmd.Flags().String("retryBackoff", "", fmt.Sprintf("The wait before the first retry of a failed phase, doubled after every retry (default '%s').", defaultRetryBackoff))
+	cmd.Flags().StringSlice("retryPhases", nil, fmt.Sprintf("Comma separated phases of the project's builds which are retried, any of 'clone', 'install' and 'build' (default '%s').", strings.Join(defaultRetryPhases, ",")))
+}
+
+// applyBuildConfigFlags updates the build configuration of the project with the flags that were
+// specified, reporting whether anything changed
+func applyBuildConfigFlags(cmd *cobra.Command, rjProject *RJProject) (bool, error) {
+	var updated bool
+
+	for flag, field := range map[string]*string{
+		"buildScript":    &rjProject.BuildScript,
+		"dockerfile":     &rjProject.Dockerfile,
+		"framework":      &rjProject.Framework,
+		"installCommand": &rjProject.InstallCommand,
+		"nodeVersion":    &rjProject.NodeVersion,
+		"outputDir":      &rjProject.OutputDir,
+		"packageManager": &rjProject.PackageManager,
+		"ref":            &rjProject.Ref,
+	} {
+		if !cmd.Flags().Changed(flag) {
+			continue
+		}
+
+		value, err := cmd.Flags().GetString(flag)
+
+		if err != nil {
+			return false, err
+		}
+
+		*field = strings.TrimSpace(value)
+		updated = true
+	}
+
+	if rjProject.Framework != "" {
+		if err := checkFramework(rjProject.Framework); err != nil {
+			return false, err
+		}
+	}
+
+	if rjProject.PackageManager != "" {
+		if err := checkPackageManager(rjProject.PackageManager); err != nil {
+			return false, err
+		}
+	}
+
+	if cmd.Flags().Changed("assetStages") {
+		assetStages, err := cmd.Flags().GetStringSlice("assetStages")
+
+		if err != nil {
+			return false, err
+		}
+
+		if err = checkAssetStages(assetStages); err != nil {
+			return false, err
+		}
+
+		rjProject.AssetStages = assetStages
+		updated = true
+	}
+
+	if cmd.Flags().Changed("hashInputs") {
+		hashInputs, err := cmd.Flags().GetStringSlice("hashInputs")
+
+		if err != nil {
+			return false, err
+		}
+
+		rjProject.HashInputs = hashInputs
+		updated = true
+	}
+
+	if cmd.Flags().Changed("inputDirs") {
+		inputDirs, err := cmd.Flags().GetStringSlice("inputDirs")
+
+		if err != nil {
+			return false, err
+		}
+
+		rjProject.InputDirs = inputDirs
+		updated = true
+	}
+
+	if cmd.Flags().Changed("retryAttempts") || cmd.Flags().Changed("retryBackoff") || cmd.Flags().Changed("retryPhases") {
+		retry := RJRetryPolicy{}
+
+		if rjProject.Retry != nil {
+			retry = *rjProject.Retry
+		}
+
+		if cmd.Flags().Changed("retryAttempts") {
+			attempts, err := cmd.Flags().GetInt("retryAttempts")
+
+			if err != nil {
+				return false, err
+			}
+
+			if attempts < 0 {
+				return false, errors.New("'--retryAttempts' can not be negative")
+			}
+
+			retry.Attempts = attempts
+		}
+
+		if cmd.Flags().Changed("retryBackoff") {
+			backoff, err := cmd.Flags().GetString("retryBackoff")
+
+			if err != nil {
+				return false, err
+			}
+
+			if backoff = strings.TrimSpace(backoff); backoff != "" {
+				if _, err = time.ParseDuration(backoff); err != nil {
+					return false, errors.Wrap(err, "'--retryBackoff' is not a valid duration")
+				}
+			}
+
+			retry.Backoff = backoff
+		}
+
+		if cmd.Flags().Changed("retryPhases") {
+			phases, err := cmd.Flags().GetStringSlice("retryPhases")
+
+			if err != nil {
+				return false, err
+			}
+
+			if err = checkRetryPhases(phases); err != nil {
+				return false, err
+			}
+
+			retry.Phases = phases
+		}
+
+		// An empty policy is removed so that the project uses the defaults
+		if retry.Attempts == 0 && retry.Backoff == "" && len(retry.Phases) == 0 {
+			rjProject.Retry = nil
+		} else {
+			rjProject.Retry = &retry
+		}
+
+		updated = true
+	}
+
+	if cmd.Flags().Changed("forbiddenFiles") || cmd.Flags().Changed("maxFileSize") || cmd.Flags().Changed("maxOutputSize") || cmd.Flags().Changed("maxTypeSize") || cmd.Flags().Changed("requiredFiles") {
+		checks := RJBuildChecks{}
+
+		if rjProject.Checks != nil {
+			checks = *rjProject.Checks
+		}
+
+		if cmd.Flags().Changed("forbiddenFiles") {
+			forbidden, err := cmd.Flags().GetStringSlice("forbiddenFiles")
+
+			if err != nil {
+				return false, err
+			}
+
+			checks.Forbidden = forbidden
+		}
+
+		if cmd.Flags().Changed("maxFileSize") {
+			maxFileSize, err := cmd.Flags().GetString("maxFileSize")
+
+			if err != nil {
+				return false, err
+			}
+
+			checks.MaxFileSize = strings.TrimSpace(maxFileSize)
+		}
+
+		if cmd.Flags().Changed("maxOutputSize") {
+			maxSize, err := cmd.Flags().GetString("maxOutputSize")
+
+			if err != nil {
+				return false, err
+			}
+
+			checks.MaxSize = strings.TrimSpace(maxSize)
+		}
+
+		if cmd.Flags().Changed("maxTypeSize") {
+			typeSizes, err := cmd.Flags().GetStringArray("maxTypeSize")
+
+			if err != nil {
+				return false, err
+			}
+
+			updatedTypeSizes := make(map[string]string)
+
+			for extension, maxSize := range checks.MaxTypeSizes {
+				updatedTypeSizes[extension] = maxSize
+			}
+
+			for _, typeSize := range typeSizes {
+				extensionSize := strings.SplitN(typeSize, "=", 2)
+
+				if len(extensionSize) != 2 || strings.TrimSpace(extensionSize[0]) == "" {
+					return false, fmt.Errorf("'--maxTypeSize' of '%s' is not in the form 'EXTENSION=SIZE'", typeSize)
+				}
+
+				if extension := strings.ToLower(strings.TrimSpace(extensionSize[0])); strings.TrimSpace(extensionSize[1]) == "" {
+					delete(updatedTypeSizes, extension)
+				} else {
+					updatedTypeSizes[extension] = strings.TrimSpace(extensionSize[1])
+				}
+			}
+
+			if len(updatedTypeSizes) == 0 {
+				updatedTypeSizes = nil
+			}
+
+			checks.MaxTypeSizes = updatedTypeSizes
+		}
+
+		if cmd.Flags().Changed("requiredFiles") {
+			required, err := cmd.Flags().GetStringSlice("requiredFiles")
+
+			if err != nil {
+				return false, err
+			}
+
+			checks.Required = required
+		}
+
+		if err := checkBuildChecks(&checks); err != nil {
+			return false, err
+		}
+
+		// Without any checks the build output is released as long as there is some
+		if len(checks.Forbidden) == 0 && checks.MaxFileSize == "" && checks.MaxSize == "" && len(checks.MaxTypeSizes) == 0 && len(checks.Required) == 0 {
+			rjProject.Checks = nil
+		} else {
+			rjProject.Checks = &checks
+		}
+
+		updated = true
+	}
+
+	if cmd.Flags().Changed("buildEnv") {
+		buildEnv, err := cmd.Flags().GetStringArray("buildEnv")
+
+		if err != nil {
+			return false, err
+		}
+
+		updatedBuildEnv := make(map[string]string)
+
+		for key, value := range rjProject.BuildEnv {
+			updatedBuildEnv[key] = value
+		}
+
+		for _, variable := range buildEnv {
+			keyValue := strings.SplitN(variable, "=", 2)
+
+			if len(keyValue) != 2 || strings.TrimSpace(keyValue[0]) == "" {
+				return false, fmt.Errorf("build environment variable '%s' is not in the form 'KEY=VALUE'", variable)
+			}
+
+			if strings.ContainsAny(keyValue[1], "\r\n") {
+				return false, fmt.Errorf("build environment variable '%s' can't contain a newline, which a Dockerfile can't set", strings.TrimSpace(keyValue[0]))
+			}
+
+			if keyValue[1] == "" {
+				delete(updatedBuildEnv, strings.TrimSpace(keyValue[0]))
+			} else {
+				updatedBuildEnv[strings.TrimSpace(keyValue[0])] = keyValue[1]
+			}
+		}
+
+		if len(updatedBuildEnv) == 0 {
+			updatedBuildEnv = nil
+		}
+
+		rjProject.BuildEnv = updatedBuildEnv
+		updated = true
+	}
+
+	return updated, nil
+}
+
 func init() {
 	updateCmd.Flags().StringP("description", "d", "", "Either updates a description manually if provided a string, otherwise the description will be fetched from the github page (In which case the '--token' arg will need to be required).")
 	updateCmd.Flags().String("localPath", "", "The string path for the updated local path for the project; checked by default (a non-existant path will not work), but can be forced.")
 	updateCmd.Flags().String("sitePath", "", "The string path for the updated local path for the project.")
+	updateCmd.Flags().Bool("rootCgo", false, "Builds the webserver with cgo, which can only build it for linux on this machine's architecture.")
+	updateCmd.Flags().String("rootGoVersion", "", fmt.Sprintf("The tag of the golang image the webserver is built with, Go 1.18 or newer and an alpine image with '--rootCgo' (default '%s').", defaultGoVersion))
+	updateCmd.Flags().String("rootLdflags", "", "Linker flags the webserver is built with, after those stamping its version and commit.")
+	updateCmd.Flags().StringSlice("rootTags", nil, "Comma separated build tags the webserver is built with.")
+	updateCmd.Flags().String("siteURL", "", "The URL the site is served under, which projects are built to be served under with their site paths; an empty string removes it. Create React App reads it from PUBLIC_URL and Vite is passed '--base', SvelteKit and Next.js projects only use it if their configs set 'kit.paths.base' from BASE_PATH and 'basePath' from NEXT_PUBLIC_BASE_PATH.")
+	addBuildConfigFlags(updateCmd)
 	updateCmd.Flags().StringP("token", "t", "", "Name of the json file in the project root with the gitlab token for gathering the project descriptions, or the token directly.")
 	rootCmd.AddCommand(updateCmd)
 }
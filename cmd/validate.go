@@ -0,0 +1,54 @@
+// Copyright © 2018 Riley Johnson rj@therileyjohnson.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Reports every structural problem found in RJglobal and RJlocal.",
+	Long: `Reports every structural problem found in RJglobal and RJlocal, exiting with a non-zero exit code if any are found.
+Checks for duplicate IDs and URLs, invalid URLs, site paths which are shared, nested or outside of the project root,
+local projects missing from RJglobal, local paths which do not exist, and .RJtag files which do not match their project's ID.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rjInfo, err := getRjInfo(projectRootPath)
+
+		if err != nil {
+			return err
+		}
+
+		problems := validateRjInfo(projectRootPath, rjInfo)
+
+		if len(problems) == 0 {
+			cmd.Println("No problems found.")
+			return nil
+		}
+
+		for _, problem := range problems {
+			cmd.Println(problem)
+		}
+
+		return fmt.Errorf("%d problem(s) found in RJglobal/RJlocal", len(problems))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}